@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	commandStub "172.21.5.249/air-trans/at-drone/internal/gapi/command_stub"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+const (
+	addressFlag string = "address"
+	rejectFlag  string = "reject"
+)
+
+var commandStubCmd = &cobra.Command{
+	Use:   "command-stub",
+	Short: "Starts a stub at_command server",
+	Long:  "Starts a gRPC server accepting the contingency commands in place of at_command, for local tests",
+	Run: func(cmd *cobra.Command, args []string) {
+		runCommandStub(cmd)
+	},
+}
+
+func init() {
+	commandStubCmd.Flags().String(addressFlag, "localhost:33365", "Listen address, the at-command grpc channel of app.yaml.")
+	commandStubCmd.Flags().Bool(rejectFlag, false, "Reject every command.")
+
+	rootCmd.AddCommand(commandStubCmd)
+}
+
+func runCommandStub(cmd *cobra.Command) {
+	ctx := log.Logger.WithContext(context.Background())
+
+	address, _ := cmd.Flags().GetString(addressFlag)
+	reject, _ := cmd.Flags().GetBool(rejectFlag)
+
+	s := &commandStub.Server{Reject: reject}
+
+	err := s.Start(address)
+	if err != nil {
+		config.PrintFatalLog(ctx, err, "Failed to start command stub")
+
+		os.Exit(1)
+	}
+}
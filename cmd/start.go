@@ -129,6 +129,39 @@ func runServer(args []string) {
 
 	errs := make(chan error, 2)
 
+	/**
+	* Start flight containment monitor
+	 */
+	config.PrintDebugLog(ctx, "Starting containment monitor...")
+
+	err = svc.StartVolumeIndex(ctx)
+	if err != nil {
+		config.PrintFatalLog(ctx, err, "Failed to start volume index")
+
+		os.Exit(1)
+	}
+
+	err = svc.StartContainmentMonitor(ctx)
+	if err != nil {
+		config.PrintFatalLog(ctx, err, "Failed to start containment monitor")
+
+		os.Exit(1)
+	}
+
+	err = svc.StartFlightRestrictions(ctx)
+	if err != nil {
+		config.PrintFatalLog(ctx, err, "Failed to start flight restrictions")
+
+		os.Exit(1)
+	}
+
+	err = svc.StartInfringementRenotify(ctx)
+	if err != nil {
+		config.PrintFatalLog(ctx, err, "Failed to start infringement re-notification")
+
+		os.Exit(1)
+	}
+
 	/**
 	* Start GRPC server
 	 */
@@ -149,7 +182,7 @@ func runServer(args []string) {
 
 	go func() {
 		c := make(chan os.Signal, 1)
-		signal.Notify(c, syscall.SIGINT)
+		signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
 
 		errs <- fmt.Errorf("%s", <-c)
 	}()
@@ -159,5 +192,7 @@ func runServer(args []string) {
 
 	err = <-errs
 
+	svc.StopContainmentMonitor(ctx)
+
 	config.PrintFatalLog(ctx, err, "Services terminate")
 }
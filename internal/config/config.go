@@ -6,14 +6,17 @@ import (
 )
 
 type ServiceConfig struct {
-	DbConfig       MongoConfig    `mapstructure:"mongo"`
-	GrpcConfig     GrpcConfig     `mapstructure:"grpc"`
-	HttpConfig     HttpConfig     `mapstructure:"http"`
-	LoggerConfig   LoggerConfig   `mapstructure:"logger"`
-	RabbitmqConfig RabbitMQConfig `mapstructure:"rabbitmq"`
-	OtherConfig    OtherConfig    `mapstructure:"other"`
-	NATSConfig     NATSConfig     `mapstructure:"nats"`
-	JWTTokenConfig JWTTokenConfig `mapstructure:"jwt_token_config"`
+	DbConfig          MongoConfig       `mapstructure:"mongo"`
+	GrpcConfig        GrpcConfig        `mapstructure:"grpc"`
+	HttpConfig        HttpConfig        `mapstructure:"http"`
+	LoggerConfig      LoggerConfig      `mapstructure:"logger"`
+	RabbitmqConfig    RabbitMQConfig    `mapstructure:"rabbitmq"`
+	OtherConfig       OtherConfig       `mapstructure:"other"`
+	NATSConfig        NATSConfig        `mapstructure:"nats"`
+	JWTTokenConfig    JWTTokenConfig    `mapstructure:"jwt_token_config"`
+	ContainmentConfig ContainmentConfig `mapstructure:"containment"`
+	TerrainConfig     TerrainConfig     `mapstructure:"terrain"`
+	GeoidConfig       GeoidConfig       `mapstructure:"geoid"`
 }
 
 func LoadConfig(path string) (cfg ServiceConfig, err error) {
@@ -102,6 +105,54 @@ func setDefaultValue() {
 	/* Config jwt */
 	viper.SetDefault("jwt_token_config.validate_jwt", false)
 
+	/* Config containment */
+	viper.SetDefault("containment.radius", 5.0)
+	viper.SetDefault("containment.interval", 1000)
+	viper.SetDefault("containment.warning_buffer", 1.0)
+	viper.SetDefault("containment.entry_hysteresis", 0.5)
+	viper.SetDefault("containment.exit_hysteresis", 0.5)
+	viper.SetDefault("containment.confirm_samples", 3)
+	viper.SetDefault("containment.window_samples", 5)
+	viper.SetDefault("containment.critical_factor", 2.0)
+	viper.SetDefault("containment.horizon", 30000)
+	viper.SetDefault("containment.prediction_step", 1000)
+	viper.SetDefault("containment.breach_threshold", 10000)
+	viper.SetDefault("containment.index_cell_size", 0.05)
+	viper.SetDefault("containment.index_refresh", 30000)
+	viper.SetDefault("containment.max_samples", 50)
+	viper.SetDefault("containment.sample_interval", 1000)
+	viper.SetDefault("containment.renotify", 60000)
+	viper.SetDefault("containment.command_timeout", 5000)
+	viper.SetDefault("containment.state_timeout", 10000)
+	viper.SetDefault("containment.separation_horizontal", 50)
+	viper.SetDefault("containment.separation_vertical", 15)
+	viper.SetDefault("containment.separation_horizon", 30000)
+	viper.SetDefault("containment.min_segment_length", 1.0)
+	viper.SetDefault("containment.max_turn_angle", 150)
+	viper.SetDefault("containment.max_climb_gradient", 1.0)
+	viper.SetDefault("containment.endurance", 1800000)
+	viper.SetDefault("containment.cruise_speed", 10.0)
+	viper.SetDefault("containment.max_bank_angle", 30.0)
+	viper.SetDefault("containment.min_terrain_clearance", 30.0)
+	viper.SetDefault("containment.horizontal_accuracy", 1.5)
+	viper.SetDefault("containment.vertical_accuracy", 3.0)
+	viper.SetDefault("containment.source_accuracy", map[string]interface{}{
+		"radar": map[string]interface{}{"horizontal": 10.0, "vertical": 20.0},
+		"ew":    map[string]interface{}{"horizontal": 50.0, "vertical": 100.0},
+		"cam":   map[string]interface{}{"horizontal": 5.0, "vertical": 10.0},
+	})
+	viper.SetDefault("containment.warning_confidence", 0.0)
+	viper.SetDefault("containment.breach_confidence", 0.0)
+
+	/* Config terrain */
+	viper.SetDefault("terrain.dir", "")
+	viper.SetDefault("terrain.cache_tiles", 8)
+
+	/* Config geoid */
+	viper.SetDefault("geoid.file", "")
+	viper.SetDefault("geoid.track_datum", "AMSL")
+	viper.SetDefault("geoid.source_datums", map[string]string{"radar": "WGS84"})
+
 	/* Config other */
 	viper.SetDefault("other.environment", "development")
 	viper.SetDefault("other.default_lang", "en")
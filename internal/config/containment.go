@@ -0,0 +1,46 @@
+package config
+
+type ContainmentConfig struct {
+	Radius          float64 `mapstructure:"radius"`           // default corridor radius in meter
+	Interval        int     `mapstructure:"interval"`         // monitor interval in millisecond
+	WarningBuffer   float64 `mapstructure:"warning_buffer"`   // warning band inside the corridor edge in meter
+	EntryHysteresis float64 `mapstructure:"entry_hysteresis"` // margin beyond the edge to enter breach in meter
+	ExitHysteresis  float64 `mapstructure:"exit_hysteresis"`  // margin inside the edge to leave breach or warning in meter
+	ConfirmSamples  int     `mapstructure:"confirm_samples"`  // N samples needed to confirm a transition
+	WindowSamples   int     `mapstructure:"window_samples"`   // M latest samples considered
+	CriticalFactor  float64 `mapstructure:"critical_factor"`  // breach beyond this multiple of the radius is critical
+	Horizon         int     `mapstructure:"horizon"`          // look-ahead of the breach prediction in millisecond
+	PredictionStep  int     `mapstructure:"prediction_step"`  // extrapolation step in millisecond
+	BreachThreshold int     `mapstructure:"breach_threshold"` // predicted time to breach that raises an alert in millisecond
+	IndexCellSize   float64 `mapstructure:"index_cell_size"`  // spatial index cell size in degree
+	IndexRefresh    int     `mapstructure:"index_refresh"`    // geofence reload interval in millisecond
+	MaxSamples      int     `mapstructure:"max_samples"`      // positions kept per infringement record
+	SampleInterval  int     `mapstructure:"sample_interval"`  // initial interval between recorded positions in millisecond
+	Renotify        int     `mapstructure:"renotify"`         // re-notify interval of unacknowledged infringements in millisecond, 0 disables
+	CommandTimeout  int     `mapstructure:"command_timeout"`  // timeout of a contingency command sent to at_command in millisecond
+	StateTimeout    int     `mapstructure:"state_timeout"`    // drones not tracked for longer lose their containment state in millisecond
+
+	SeparationHorizontal float64 `mapstructure:"separation_horizontal"` // horizontal separation minimum between drones in meter, 0 disables
+	SeparationVertical   float64 `mapstructure:"separation_vertical"`   // vertical separation minimum between drones in meter
+	SeparationHorizon    int     `mapstructure:"separation_horizon"`    // look-ahead of the separation conflict prediction in millisecond
+
+	MinSegmentLength float64 `mapstructure:"min_segment_length"` // shorter route segments are zero-length in meter
+	MaxTurnAngle     float64 `mapstructure:"max_turn_angle"`     // sharpest heading change at a waypoint in degree, 0 disables
+	MaxClimbGradient float64 `mapstructure:"max_climb_gradient"` // steepest climb or descent over horizontal distance, 0 disables
+	Endurance        int     `mapstructure:"endurance"`          // flight time on a full battery in millisecond, 0 disables
+	CruiseSpeed      float64 `mapstructure:"cruise_speed"`       // meter per second for the drones without MaxSpeed
+	MaxBankAngle     float64 `mapstructure:"max_bank_angle"`     // bank angle of fly-by turns in degree for the corridors without one
+
+	MinTerrainClearance float64 `mapstructure:"min_terrain_clearance"` // height above the ground the drones keep in meter
+
+	HorizontalAccuracy float64                  `mapstructure:"horizontal_accuracy"` // 1-sigma horizontal error per axis of the tracks without source accuracy in meter
+	VerticalAccuracy   float64                  `mapstructure:"vertical_accuracy"`   // 1-sigma vertical error of the tracks without source accuracy in meter
+	SourceAccuracy     map[string]TrackAccuracy `mapstructure:"source_accuracy"`     // accuracy by source type of the track: radar, ew or cam
+	WarningConfidence  float64                  `mapstructure:"warning_confidence"`  // probability past the warning threshold that counts a warning sample, 0 compares the measured deviation
+	BreachConfidence   float64                  `mapstructure:"breach_confidence"`   // probability past the breach threshold that counts a breach sample, 0 compares the measured deviation
+}
+
+type TrackAccuracy struct {
+	Horizontal float64 `mapstructure:"horizontal"` // 1-sigma per axis in meter
+	Vertical   float64 `mapstructure:"vertical"`   // 1-sigma in meter
+}
@@ -24,6 +24,9 @@ const RSC_USER string = "user"
 const RSC_DATASOURCE string = "datasource"
 const RSC_OBJECT_TRACK string = "object_track"
 const RSC_TRACK_HISTORY string = "track_history"
+const RSC_CORRIDOR string = "corridor"
+const RSC_GEOFENCE string = "geofence"
+const RSC_FLIGHT_RESTRICTION string = "flight_restriction"
 
 
 /*************** Action name ***************/
@@ -31,3 +34,4 @@ const ACT_CREATE string = "create"
 const ACT_UPDATE string = "update"
 const ACT_PATCH string = "patch"
 const ACT_DELETE string = "delete"
+const ACT_AMEND string = "amend"
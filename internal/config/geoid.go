@@ -0,0 +1,7 @@
+package config
+
+type GeoidConfig struct {
+	File         string            `mapstructure:"file"`          // EGM96 or EGM2008 grid, GeographicLib .pgm or NGA .grd, empty takes AMSL heights as WGS84 ones
+	TrackDatum   string            `mapstructure:"track_datum"`   // AMSL or WGS84, vertical datum of the track altitudes
+	SourceDatums map[string]string `mapstructure:"source_datums"` // vertical datum by source type of the track: radar, ew or cam
+}
@@ -0,0 +1,6 @@
+package config
+
+type TerrainConfig struct {
+	Dir        string `mapstructure:"dir"`         // directory of the SRTM .hgt and GeoTIFF DEM tiles, empty disables
+	CacheTiles int    `mapstructure:"cache_tiles"` // DEM tiles kept in memory
+}
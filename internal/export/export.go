@@ -0,0 +1,337 @@
+// Package export writes airspace volumes and flown tracks as GeoJSON or KML for map
+// and briefing tools.
+//
+// Latitudes and longitudes are in degree, altitudes in meter AMSL and timestamps in
+// millisecond.
+package export
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatGeoJSON Format = "geojson"
+	FormatKML     Format = "kml"
+)
+
+const (
+	MIMEGeoJSON = "application/geo+json"
+	MIMEKML     = "application/vnd.google-earth.kml+xml"
+)
+
+// Negotiate picks the format from an explicit format, then from the Accept header,
+// GeoJSON by default.
+func Negotiate(format string, accept string) (Format, error) {
+	switch strings.ToLower(format) {
+	case "geojson", "json":
+		return FormatGeoJSON, nil
+	case "kml":
+		return FormatKML, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	if strings.Contains(accept, MIMEKML) || strings.Contains(accept, "kml") {
+		return FormatKML, nil
+	}
+
+	return FormatGeoJSON, nil
+}
+
+func (f Format) ContentType() string {
+	if f == FormatKML {
+		return MIMEKML
+	}
+
+	return MIMEGeoJSON
+}
+
+type Position struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+	Timestamp uint64 // 0 when the position has no time
+}
+
+// Volume is the vertical extrusion of a ring between Floor and Ceiling. The ring is
+// not closed, its first vertex is repeated on output.
+type Volume struct {
+	Ring    []Position
+	Floor   float64
+	Ceiling float64
+}
+
+type Feature struct {
+	Name       string
+	Properties map[string]interface{}
+	Volumes    []Volume
+	Track      []Position
+}
+
+type Document struct {
+	Name     string
+	Features []Feature
+}
+
+func (d *Document) Write(w io.Writer, format Format) error {
+	if format == FormatKML {
+		return d.WriteKML(w)
+	}
+
+	return d.WriteGeoJSON(w)
+}
+
+func closeRing(ring []Position) []Position {
+	if len(ring) == 0 || ring[0] == ring[len(ring)-1] {
+		return ring
+	}
+
+	return append(ring[:len(ring):len(ring)], ring[0])
+}
+
+/***************************************************************************************************************/
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONCollection struct {
+	Type     string           `json:"type"`
+	Name     string           `json:"name,omitempty"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+func properties(f Feature, extra map[string]interface{}) map[string]interface{} {
+	p := map[string]interface{}{"name": f.Name}
+	for k, v := range f.Properties {
+		p[k] = v
+	}
+	for k, v := range extra {
+		p[k] = v
+	}
+
+	return p
+}
+
+// WriteGeoJSON writes a FeatureCollection with one Polygon per volume, carrying
+// altitude_floor and altitude_ceiling, and one LineString per track with the
+// timestamps of its positions.
+func (d *Document) WriteGeoJSON(w io.Writer) error {
+	collection := geoJSONCollection{Type: "FeatureCollection", Name: d.Name, Features: []geoJSONFeature{}}
+
+	for _, f := range d.Features {
+		for i, v := range f.Volumes {
+			ring := [][]float64{}
+			for _, p := range closeRing(v.Ring) {
+				ring = append(ring, []float64{p.Longitude, p.Latitude})
+			}
+
+			collection.Features = append(collection.Features, geoJSONFeature{
+				Type:     "Feature",
+				Geometry: geoJSONGeometry{Type: "Polygon", Coordinates: [][][]float64{ring}},
+				Properties: properties(f, map[string]interface{}{
+					"part":             i,
+					"altitude_floor":   v.Floor,
+					"altitude_ceiling": v.Ceiling,
+				}),
+			})
+		}
+
+		if len(f.Track) == 0 {
+			continue
+		}
+
+		line := [][]float64{}
+		timestamps := []uint64{}
+		for _, p := range f.Track {
+			line = append(line, []float64{p.Longitude, p.Latitude, p.Altitude})
+			timestamps = append(timestamps, p.Timestamp)
+		}
+
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "LineString", Coordinates: line},
+			Properties: properties(f, map[string]interface{}{"timestamps": timestamps}),
+		})
+	}
+
+	return json.NewEncoder(w).Encode(collection)
+}
+
+/***************************************************************************************************************/
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlPolygon struct {
+	AltitudeMode string `xml:"altitudeMode"`
+	Coordinates  string `xml:"outerBoundaryIs>LinearRing>coordinates"`
+}
+
+type kmlLineString struct {
+	AltitudeMode string `xml:"altitudeMode"`
+	Coordinates  string `xml:"coordinates"`
+}
+
+type kmlTrack struct {
+	AltitudeMode string   `xml:"altitudeMode"`
+	When         []string `xml:"when"`
+	Coords       []string `xml:"gx:coord"`
+}
+
+type kmlMultiGeometry struct {
+	Polygons []kmlPolygon `xml:"Polygon"`
+}
+
+type kmlPlacemark struct {
+	Name          string            `xml:"name"`
+	ExtendedData  []kmlData         `xml:"ExtendedData>Data,omitempty"`
+	MultiGeometry *kmlMultiGeometry `xml:"MultiGeometry,omitempty"`
+	LineString    *kmlLineString    `xml:"LineString,omitempty"`
+	Track         *kmlTrack         `xml:"gx:Track,omitempty"`
+}
+
+type kmlRoot struct {
+	XMLName    xml.Name       `xml:"kml"`
+	Xmlns      string         `xml:"xmlns,attr"`
+	XmlnsGx    string         `xml:"xmlns:gx,attr"`
+	Name       string         `xml:"Document>name"`
+	Placemarks []kmlPlacemark `xml:"Document>Placemark"`
+}
+
+func kmlCoordinates(positions []Position, altitude func(Position) float64) string {
+	tuples := make([]string, 0, len(positions))
+	for _, p := range positions {
+		tuples = append(tuples, fmt.Sprintf("%v,%v,%v", p.Longitude, p.Latitude, altitude(p)))
+	}
+
+	return strings.Join(tuples, " ")
+}
+
+func kmlExtendedData(f Feature, extra map[string]interface{}) []kmlData {
+	p := properties(f, extra)
+	delete(p, "name")
+	if len(p) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	data := make([]kmlData, 0, len(keys))
+	for _, k := range keys {
+		data = append(data, kmlData{Name: k, Value: fmt.Sprint(p[k])})
+	}
+
+	return data
+}
+
+// kmlVolume draws the volume as its floor, its ceiling and one wall per edge.
+func kmlVolume(v Volume) *kmlMultiGeometry {
+	ring := closeRing(v.Ring)
+	at := func(alt float64) func(Position) float64 {
+		return func(Position) float64 { return alt }
+	}
+
+	g := &kmlMultiGeometry{Polygons: []kmlPolygon{
+		{AltitudeMode: "absolute", Coordinates: kmlCoordinates(ring, at(v.Floor))},
+		{AltitudeMode: "absolute", Coordinates: kmlCoordinates(ring, at(v.Ceiling))},
+	}}
+
+	for i := 0; i+1 < len(ring); i++ {
+		a, b := ring[i], ring[i+1]
+		wall := []Position{a, b, b, a, a}
+		alts := []float64{v.Floor, v.Floor, v.Ceiling, v.Ceiling, v.Floor}
+
+		tuples := make([]string, 0, len(wall))
+		for j, p := range wall {
+			tuples = append(tuples, fmt.Sprintf("%v,%v,%v", p.Longitude, p.Latitude, alts[j]))
+		}
+
+		g.Polygons = append(g.Polygons, kmlPolygon{AltitudeMode: "absolute", Coordinates: strings.Join(tuples, " ")})
+	}
+
+	return g
+}
+
+// WriteKML writes one Placemark per volume and per track. A track whose positions
+// all have a time becomes a gx:Track, a LineString otherwise.
+func (d *Document) WriteKML(w io.Writer) error {
+	root := kmlRoot{
+		Xmlns:   "http://www.opengis.net/kml/2.2",
+		XmlnsGx: "http://www.google.com/kml/ext/2.2",
+		Name:    d.Name,
+	}
+
+	for _, f := range d.Features {
+		for i, v := range f.Volumes {
+			root.Placemarks = append(root.Placemarks, kmlPlacemark{
+				Name: f.Name,
+				ExtendedData: kmlExtendedData(f, map[string]interface{}{
+					"part":             i,
+					"altitude_floor":   v.Floor,
+					"altitude_ceiling": v.Ceiling,
+				}),
+				MultiGeometry: kmlVolume(v),
+			})
+		}
+
+		if len(f.Track) == 0 {
+			continue
+		}
+
+		placemark := kmlPlacemark{Name: f.Name, ExtendedData: kmlExtendedData(f, nil)}
+
+		timed := true
+		for _, p := range f.Track {
+			timed = timed && p.Timestamp > 0
+		}
+
+		if timed {
+			track := &kmlTrack{AltitudeMode: "absolute"}
+			for _, p := range f.Track {
+				track.When = append(track.When, time.UnixMilli(int64(p.Timestamp)).UTC().Format(time.RFC3339Nano))
+				track.Coords = append(track.Coords, fmt.Sprintf("%v %v %v", p.Longitude, p.Latitude, p.Altitude))
+			}
+			placemark.Track = track
+		} else {
+			placemark.LineString = &kmlLineString{
+				AltitudeMode: "absolute",
+				Coordinates:  kmlCoordinates(f.Track, func(p Position) float64 { return p.Altitude }),
+			}
+		}
+
+		root.Placemarks = append(root.Placemarks, placemark)
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	return encoder.Encode(root)
+}
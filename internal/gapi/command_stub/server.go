@@ -0,0 +1,55 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	logger "172.21.5.249/air-trans/at-drone/internal/gapi/middleware"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// Server stands in for at_command in local tests. It logs the contingency commands
+// it receives and accepts them, or rejects them all when Reject is set.
+type Server struct {
+	pb.UnimplementedContingencyCommandServiceServer
+	Reject bool
+}
+
+func (s *Server) Dispatch(ctx context.Context, command *pb.ContingencyCommand) (*pb.ContingencyCommandResponse, error) {
+	config.PrintInfoLog(ctx, "Received contingency command: %s - drone: %s - action: %s", command.ID, command.DroneID, command.Action)
+
+	if s.Reject {
+		return &pb.ContingencyCommandResponse{
+			Accepted: false,
+			Message:  "rejected by command stub",
+		}, nil
+	}
+
+	return &pb.ContingencyCommandResponse{
+		Accepted: true,
+		Message:  "accepted by command stub",
+	}, nil
+}
+
+func (s *Server) Start(address string) error {
+	ctx := log.Logger.WithContext(context.Background())
+
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(logger.LoggerMiddleware))
+	pb.RegisterContingencyCommandServiceServer(grpcServer, s)
+	reflection.Register(grpcServer)
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("cannot create grpc listener: %w", err)
+	}
+
+	config.PrintInfoLog(ctx, "Start command stub on: %s", listener.Addr().String())
+
+	return grpcServer.Serve(listener)
+}
@@ -0,0 +1,152 @@
+package corridor
+
+import (
+	"context"
+	"encoding/json"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	gcommon "172.21.5.249/air-trans/at-drone/internal/gapi/common"
+	service "172.21.5.249/air-trans/at-drone/internal/service"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	jsonpatch "github.com/evanphx/json-patch"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type CorridorHandler struct {
+	pb.UnimplementedCorridorServiceServer
+	MainService *service.MainService
+}
+
+func NewCorridorHandler(svc *service.MainService) *CorridorHandler {
+	return &CorridorHandler{
+		MainService: svc,
+	}
+}
+
+func (h *CorridorHandler) Create(ctx context.Context, ass *pb.ContainmentCorridor) (*pb.ContainmentCorridor, error) {
+	requestID := uuid.NewString()
+	ctx = log.With().Str("x-request-id", requestID).Logger().WithContext(ctx)
+
+	eventAPI := gcommon.GetEventAPIFromContext(ctx)
+	if !eventAPI {
+		ass.ID = requestID
+	}
+
+	config.PrintDebugLog(ctx, "Create corridor: %+v", ass)
+
+	result, err := h.MainService.CreateCorridor(ctx, ass, eventAPI)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to create corridor: %+v", ass)
+
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (h *CorridorHandler) Update(ctx context.Context, ass *pb.ContainmentCorridor) (*pb.ContainmentCorridor, error) {
+	requestID := uuid.NewString()
+	ctx = log.With().Str("x-request-id", requestID).Logger().WithContext(ctx)
+
+	eventAPI := gcommon.GetEventAPIFromContext(ctx)
+
+	config.PrintDebugLog(ctx, "Update corridor by id: %s: %+v", ass.ID, ass)
+
+	result, err := h.MainService.UpdateCorridorByID(ctx, ass, ass.ID, eventAPI)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to update corridor by id: %s: %+v", ass.ID, ass)
+
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (h *CorridorHandler) Search(ctx context.Context, so *pb.SearchOptions) (*pb.SearchContainmentCorridorResponse, error) {
+	requestID := uuid.NewString()
+	ctx = log.With().Str("x-request-id", requestID).Logger().WithContext(ctx)
+
+	opt := gcommon.ParseQueryOptions(so)
+
+	config.PrintDebugLog(ctx, "Search corridor: %+v", opt)
+
+	result, total := h.MainService.SearchCorridor(ctx, opt)
+
+	config.PrintDebugLog(ctx, "Search corridor result: %d", total)
+
+	response := searchResponse(*result, total)
+
+	return response, nil
+}
+
+func (h *CorridorHandler) Patch(ctx context.Context, po *pb.PatchOptions) (*pb.PatchResponse, error) {
+	requestID := uuid.NewString()
+	ctx = log.With().Str("x-request-id", requestID).Logger().WithContext(ctx)
+	var patch jsonpatch.Patch
+	err := json.Unmarshal(po.Operations, &patch)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to parse patch option: %+v", po)
+
+		return &pb.PatchResponse{
+			IsOk:    false,
+			Message: err.Error(),
+		}, err
+	}
+
+	eventAPI := gcommon.GetEventAPIFromContext(ctx)
+
+	config.PrintDebugLog(ctx, "Patch corridor by id: %s: %+v", po.ID, patch)
+
+	_, err = h.MainService.PatchCorridorByID(ctx, &patch, po.ID, eventAPI)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to patch corridor by id: %s: %+v", po.ID, patch)
+
+		return &pb.PatchResponse{
+			IsOk:    false,
+			Message: err.Error(),
+		}, err
+	}
+
+	return &pb.PatchResponse{
+		IsOk: true,
+	}, nil
+}
+
+func (h *CorridorHandler) Delete(ctx context.Context, do *pb.DeleteOptions) (*pb.DeleteResponse, error) {
+	requestID := uuid.NewString()
+	ctx = log.With().Str("x-request-id", requestID).Logger().WithContext(ctx)
+
+	eventAPI := gcommon.GetEventAPIFromContext(ctx)
+
+	config.PrintDebugLog(ctx, "Delete corridor by id: %s", do.ID)
+
+	err := h.MainService.DeleteCorridorByID(ctx, do.ID, eventAPI)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to delete corridor by id: %s", do.ID)
+
+		return &pb.DeleteResponse{
+			IsOk:    false,
+			Message: err.Error(),
+		}, err
+	}
+
+	return &pb.DeleteResponse{
+		IsOk: true,
+	}, nil
+}
+
+/* Create GRPC search response */
+func searchResponse(r []pb.ContainmentCorridor, t int64) *pb.SearchContainmentCorridorResponse {
+	gresult := make([]*pb.ContainmentCorridor, len(r))
+	for i := range r {
+		gresult[i] = &r[i]
+	}
+
+	return &pb.SearchContainmentCorridorResponse{
+		Corridors:  gresult,
+		TotalCount: int32(t),
+	}
+}
@@ -0,0 +1,152 @@
+package geofence
+
+import (
+	"context"
+	"encoding/json"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	gcommon "172.21.5.249/air-trans/at-drone/internal/gapi/common"
+	service "172.21.5.249/air-trans/at-drone/internal/service"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	jsonpatch "github.com/evanphx/json-patch"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type GeofenceHandler struct {
+	pb.UnimplementedGeofenceServiceServer
+	MainService *service.MainService
+}
+
+func NewGeofenceHandler(svc *service.MainService) *GeofenceHandler {
+	return &GeofenceHandler{
+		MainService: svc,
+	}
+}
+
+func (h *GeofenceHandler) Create(ctx context.Context, ass *pb.Geofence) (*pb.Geofence, error) {
+	requestID := uuid.NewString()
+	ctx = log.With().Str("x-request-id", requestID).Logger().WithContext(ctx)
+
+	eventAPI := gcommon.GetEventAPIFromContext(ctx)
+	if !eventAPI {
+		ass.ID = requestID
+	}
+
+	config.PrintDebugLog(ctx, "Create geofence: %+v", ass)
+
+	result, err := h.MainService.CreateGeofence(ctx, ass, eventAPI)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to create geofence: %+v", ass)
+
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (h *GeofenceHandler) Update(ctx context.Context, ass *pb.Geofence) (*pb.Geofence, error) {
+	requestID := uuid.NewString()
+	ctx = log.With().Str("x-request-id", requestID).Logger().WithContext(ctx)
+
+	eventAPI := gcommon.GetEventAPIFromContext(ctx)
+
+	config.PrintDebugLog(ctx, "Update geofence by id: %s: %+v", ass.ID, ass)
+
+	result, err := h.MainService.UpdateGeofenceByID(ctx, ass, ass.ID, eventAPI)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to update geofence by id: %s: %+v", ass.ID, ass)
+
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (h *GeofenceHandler) Search(ctx context.Context, so *pb.SearchOptions) (*pb.SearchGeofenceResponse, error) {
+	requestID := uuid.NewString()
+	ctx = log.With().Str("x-request-id", requestID).Logger().WithContext(ctx)
+
+	opt := gcommon.ParseQueryOptions(so)
+
+	config.PrintDebugLog(ctx, "Search geofence: %+v", opt)
+
+	result, total := h.MainService.SearchGeofence(ctx, opt)
+
+	config.PrintDebugLog(ctx, "Search geofence result: %d", total)
+
+	response := searchResponse(*result, total)
+
+	return response, nil
+}
+
+func (h *GeofenceHandler) Patch(ctx context.Context, po *pb.PatchOptions) (*pb.PatchResponse, error) {
+	requestID := uuid.NewString()
+	ctx = log.With().Str("x-request-id", requestID).Logger().WithContext(ctx)
+	var patch jsonpatch.Patch
+	err := json.Unmarshal(po.Operations, &patch)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to parse patch option: %+v", po)
+
+		return &pb.PatchResponse{
+			IsOk:    false,
+			Message: err.Error(),
+		}, err
+	}
+
+	eventAPI := gcommon.GetEventAPIFromContext(ctx)
+
+	config.PrintDebugLog(ctx, "Patch geofence by id: %s: %+v", po.ID, patch)
+
+	_, err = h.MainService.PatchGeofenceByID(ctx, &patch, po.ID, eventAPI)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to patch geofence by id: %s: %+v", po.ID, patch)
+
+		return &pb.PatchResponse{
+			IsOk:    false,
+			Message: err.Error(),
+		}, err
+	}
+
+	return &pb.PatchResponse{
+		IsOk: true,
+	}, nil
+}
+
+func (h *GeofenceHandler) Delete(ctx context.Context, do *pb.DeleteOptions) (*pb.DeleteResponse, error) {
+	requestID := uuid.NewString()
+	ctx = log.With().Str("x-request-id", requestID).Logger().WithContext(ctx)
+
+	eventAPI := gcommon.GetEventAPIFromContext(ctx)
+
+	config.PrintDebugLog(ctx, "Delete geofence by id: %s", do.ID)
+
+	err := h.MainService.DeleteGeofenceByID(ctx, do.ID, eventAPI)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to delete geofence by id: %s", do.ID)
+
+		return &pb.DeleteResponse{
+			IsOk:    false,
+			Message: err.Error(),
+		}, err
+	}
+
+	return &pb.DeleteResponse{
+		IsOk: true,
+	}, nil
+}
+
+/* Create GRPC search response */
+func searchResponse(r []pb.Geofence, t int64) *pb.SearchGeofenceResponse {
+	gresult := make([]*pb.Geofence, len(r))
+	for i := range r {
+		gresult[i] = &r[i]
+	}
+
+	return &pb.SearchGeofenceResponse{
+		Geofences:  gresult,
+		TotalCount: int32(t),
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"net"
 
 	config "172.21.5.249/air-trans/at-drone/internal/config"
+	corridor "172.21.5.249/air-trans/at-drone/internal/gapi/corridor"
+	geofence "172.21.5.249/air-trans/at-drone/internal/gapi/geofence"
 	drone "172.21.5.249/air-trans/at-drone/internal/gapi/drone"
 	droneTrack "172.21.5.249/air-trans/at-drone/internal/gapi/track_history"
 	logger "172.21.5.249/air-trans/at-drone/internal/gapi/middleware"
@@ -39,8 +41,12 @@ func (s *Server) Start(errs chan error) {
 
 	droneHandler := drone.NewDroneHandler(s.MainService)
 	droneTrackHandler := droneTrack.NewTrackHistoryHandler(s.MainService)
+	corridorHandler := corridor.NewCorridorHandler(s.MainService)
+	geofenceHandler := geofence.NewGeofenceHandler(s.MainService)
 	pb.RegisterDroneServiceServer(grpcServer, droneHandler)
 	pb.RegisterTrackHistoryServiceServer(grpcServer, droneTrackHandler)
+	pb.RegisterCorridorServiceServer(grpcServer, corridorHandler)
+	pb.RegisterGeofenceServiceServer(grpcServer, geofenceHandler)
 
 	reflection.Register(grpcServer)
 
@@ -0,0 +1,197 @@
+package geo
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	vincentyTolerance     = 1e-12
+	vincentyMaxIterations = 200
+)
+
+var ErrNoConvergence = errors.New("geo: vincenty formula failed to converge")
+
+// Inverse solves the inverse geodesic problem on the WGS84 ellipsoid with Vincenty's
+// formulae. It returns the distance and the forward azimuths at both points.
+//
+// The iteration does not converge for some nearly antipodal points, such as (0, 0) and
+// (0.5, 179.7). Inverse then returns zero distance and azimuths with ErrNoConvergence,
+// and Distance falls back to the great circle distance.
+func Inverse(lat1, lon1, lat2, lon2 float64) (distance, azimuth1, azimuth2 float64, err error) {
+	L := Rad(lon2 - lon1)
+	U1 := math.Atan((1 - F) * math.Tan(Rad(lat1)))
+	U2 := math.Atan((1 - F) * math.Tan(Rad(lat2)))
+	sinU1, cosU1 := math.Sincos(U1)
+	sinU2, cosU2 := math.Sincos(U2)
+
+	lambda := L
+	var sinSigma, cosSigma, sigma, cosSqAlpha, cos2SigmaM, sinLambda, cosLambda float64
+
+	converged := false
+	for i := 0; i < vincentyMaxIterations; i++ {
+		sinLambda, cosLambda = math.Sincos(lambda)
+
+		sinSigma = math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			// coincident points
+			return 0, 0, 0, nil
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha = 1 - sinAlpha*sinAlpha
+
+		cos2SigmaM = 0
+		if cosSqAlpha != 0 {
+			// equatorial line otherwise
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		}
+
+		C := F / 16 * cosSqAlpha * (4 + F*(4-3*cosSqAlpha))
+		previous := lambda
+		lambda = L + (1-C)*F*sinAlpha*(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+
+		if math.Abs(lambda-previous) < vincentyTolerance {
+			converged = true
+			break
+		}
+	}
+	if !converged {
+		return 0, 0, 0, ErrNoConvergence
+	}
+
+	uSq := cosSqAlpha * EP2
+	bigA := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+	bigB := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+	deltaSigma := bigB * sinSigma * (cos2SigmaM + bigB/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+		bigB/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+
+	distance = B * bigA * (sigma - deltaSigma)
+	azimuth1 = normalizeAzimuth(math.Atan2(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda))
+	azimuth2 = normalizeAzimuth(math.Atan2(cosU1*sinLambda, -sinU1*cosU2+cosU1*sinU2*cosLambda))
+
+	return distance, azimuth1, azimuth2, nil
+}
+
+// Direct solves the direct geodesic problem on the WGS84 ellipsoid with Vincenty's
+// formulae: the point reached after distance meters along azimuth, and the azimuth there.
+func Direct(lat, lon, azimuth, distance float64) (lat2, lon2, azimuth2 float64) {
+	sinAlpha1, cosAlpha1 := math.Sincos(azimuth)
+
+	tanU1 := (1 - F) * math.Tan(Rad(lat))
+	cosU1 := 1 / math.Sqrt(1+tanU1*tanU1)
+	sinU1 := tanU1 * cosU1
+
+	sigma1 := math.Atan2(tanU1, cosAlpha1)
+	sinAlpha := cosU1 * sinAlpha1
+	cosSqAlpha := 1 - sinAlpha*sinAlpha
+	uSq := cosSqAlpha * EP2
+	bigA := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+	bigB := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+
+	sigma := distance / (B * bigA)
+	var sinSigma, cosSigma, cos2SigmaM float64
+	for i := 0; i < vincentyMaxIterations; i++ {
+		cos2SigmaM = math.Cos(2*sigma1 + sigma)
+		sinSigma, cosSigma = math.Sincos(sigma)
+
+		deltaSigma := bigB * sinSigma * (cos2SigmaM + bigB/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+			bigB/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+
+		previous := sigma
+		sigma = distance/(B*bigA) + deltaSigma
+		if math.Abs(sigma-previous) < vincentyTolerance {
+			break
+		}
+	}
+	sinSigma, cosSigma = math.Sincos(sigma)
+	cos2SigmaM = math.Cos(2*sigma1 + sigma)
+
+	x := sinU1*sinSigma - cosU1*cosSigma*cosAlpha1
+	lat2R := math.Atan2(sinU1*cosSigma+cosU1*sinSigma*cosAlpha1, (1-F)*math.Hypot(sinAlpha, x))
+	lambda := math.Atan2(sinSigma*sinAlpha1, cosU1*cosSigma-sinU1*sinSigma*cosAlpha1)
+	C := F / 16 * cosSqAlpha * (4 + F*(4-3*cosSqAlpha))
+	L := lambda - (1-C)*F*sinAlpha*(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+
+	lon2 = math.Mod(lon+Deg(L)+540, 360) - 180
+	azimuth2 = normalizeAzimuth(math.Atan2(sinAlpha, -x))
+
+	return Deg(lat2R), lon2, azimuth2
+}
+
+// Distance returns the geodesic distance in meter, falling back to the great circle
+// distance on the mean sphere when Vincenty's formulae do not converge.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	d, _, _, err := Inverse(lat1, lon1, lat2, lon2)
+	if err != nil {
+		return haversine(lat1, lon1, lat2, lon2)
+	}
+
+	return d
+}
+
+// Destination returns the point reached after distance meters along azimuth.
+func Destination(lat, lon, azimuth, distance float64) (float64, float64) {
+	lat2, lon2, _ := Direct(lat, lon, azimuth, distance)
+
+	return lat2, lon2
+}
+
+// CrossTrackDistance returns the distance of a point from the geodesic going through
+// start and end, positive right of the path. Distances and azimuths come from the
+// ellipsoid, the spherical triangle is solved on the mean sphere.
+func CrossTrackDistance(lat, lon, startLat, startLon, endLat, endLon float64) (float64, error) {
+	d13, az13, _, err := Inverse(startLat, startLon, lat, lon)
+	if err != nil {
+		return 0, err
+	}
+	_, az12, _, err := Inverse(startLat, startLon, endLat, endLon)
+	if err != nil {
+		return 0, err
+	}
+
+	return math.Asin(math.Sin(d13/MeanRadius)*math.Sin(az13-az12)) * MeanRadius, nil
+}
+
+// AlongTrackDistance returns the distance from start to the projection of the point
+// on the geodesic going through start and end, negative behind start.
+func AlongTrackDistance(lat, lon, startLat, startLon, endLat, endLon float64) (float64, error) {
+	d13, az13, _, err := Inverse(startLat, startLon, lat, lon)
+	if err != nil {
+		return 0, err
+	}
+	_, az12, _, err := Inverse(startLat, startLon, endLat, endLon)
+	if err != nil {
+		return 0, err
+	}
+
+	delta13 := d13 / MeanRadius
+	deltaXT := math.Asin(math.Sin(delta13) * math.Sin(az13-az12))
+	along := math.Acos(math.Max(-1, math.Min(1, math.Cos(delta13)/math.Cos(deltaXT)))) * MeanRadius
+
+	if math.Cos(az13-az12) < 0 {
+		return -along, nil
+	}
+
+	return along, nil
+}
+
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := Rad(lat2 - lat1)
+	dLon := Rad(lon2 - lon1)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(Rad(lat1))*math.Cos(Rad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * MeanRadius * math.Asin(math.Sqrt(h))
+}
+
+func normalizeAzimuth(azimuth float64) float64 {
+	azimuth = math.Mod(azimuth, 2*math.Pi)
+	if azimuth < 0 {
+		azimuth += 2 * math.Pi
+	}
+
+	return azimuth
+}
@@ -0,0 +1,158 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+// dms converts degree, minute and second to degree.
+func dms(d, m, s float64) float64 {
+	if d < 0 {
+		return d - m/60 - s/3600
+	}
+
+	return d + m/60 + s/3600
+}
+
+// Vincenty's test lines, the azimuths being the forward ones at both ends.
+var geodesicTests = []struct {
+	name               string
+	lat1, lon1         float64
+	lat2, lon2         float64
+	distance           float64
+	azimuth1, azimuth2 float64 // degree
+	distanceTolerance  float64 // meter
+	azimuthTolerance   float64 // degree
+}{
+	{
+		name: "flinders peak to buninyong",
+		lat1: dms(-37, 57, 3.72030), lon1: dms(144, 25, 29.52440),
+		lat2: dms(-37, 39, 10.15610), lon2: dms(143, 55, 35.38390),
+		distance: 54972.271,
+		azimuth1: dms(306, 52, 5.37), azimuth2: dms(127, 10, 25.07) + 180,
+		distanceTolerance: 0.001, azimuthTolerance: 0.01 / 3600,
+	},
+	{
+		name: "one degree along the equator",
+		lat1: 0, lon1: 0, lat2: 0, lon2: 1,
+		distance: 111319.491,
+		azimuth1: 90, azimuth2: 90,
+		distanceTolerance: 0.001, azimuthTolerance: 1e-9,
+	},
+	{
+		name: "one degree along the meridian",
+		lat1: 0, lon1: 0, lat2: 1, lon2: 0,
+		distance: 110574.389,
+		azimuth1: 0, azimuth2: 0,
+		distanceTolerance: 0.001, azimuthTolerance: 1e-9,
+	},
+}
+
+// azimuthDiff returns the difference of two azimuths in degree, wrapped to [-180, 180].
+func azimuthDiff(a, b float64) float64 {
+	return math.Remainder(a-b, 360)
+}
+
+func TestInverse(t *testing.T) {
+	for _, tt := range geodesicTests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance, azimuth1, azimuth2, err := Inverse(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			if err != nil {
+				t.Fatalf("Inverse() error = %v", err)
+			}
+
+			if math.Abs(distance-tt.distance) > tt.distanceTolerance {
+				t.Errorf("distance = %.4f, want %.4f", distance, tt.distance)
+			}
+			if d := azimuthDiff(Deg(azimuth1), tt.azimuth1); math.Abs(d) > tt.azimuthTolerance {
+				t.Errorf("azimuth1 = %.8f, want %.8f", Deg(azimuth1), tt.azimuth1)
+			}
+			if d := azimuthDiff(Deg(azimuth2), tt.azimuth2); math.Abs(d) > tt.azimuthTolerance {
+				t.Errorf("azimuth2 = %.8f, want %.8f", Deg(azimuth2), tt.azimuth2)
+			}
+		})
+	}
+}
+
+func TestDirect(t *testing.T) {
+	for _, tt := range geodesicTests {
+		t.Run(tt.name, func(t *testing.T) {
+			lat2, lon2, azimuth2 := Direct(tt.lat1, tt.lon1, Rad(tt.azimuth1), tt.distance)
+
+			// 1e-8 degree is about a millimeter
+			if math.Abs(lat2-tt.lat2) > 1e-8 || math.Abs(lon2-tt.lon2) > 1e-8 {
+				t.Errorf("Direct() = %.9f, %.9f, want %.9f, %.9f", lat2, lon2, tt.lat2, tt.lon2)
+			}
+			if d := azimuthDiff(Deg(azimuth2), tt.azimuth2); math.Abs(d) > tt.azimuthTolerance {
+				t.Errorf("azimuth2 = %.8f, want %.8f", Deg(azimuth2), tt.azimuth2)
+			}
+		})
+	}
+}
+
+func TestInverseNearlyAntipodal(t *testing.T) {
+	_, _, _, err := Inverse(0, 0, 0.5, 179.7)
+	if err != ErrNoConvergence {
+		t.Fatalf("Inverse() error = %v, want %v", err, ErrNoConvergence)
+	}
+
+	if d := Distance(0, 0, 0.5, 179.7); d < 19e6 || d > 20.1e6 {
+		t.Errorf("Distance() = %.0f, want the great circle distance", d)
+	}
+}
+
+func TestCrossTrackDistance(t *testing.T) {
+	// Path heading north along the 105 degree meridian
+	startLat, startLon, endLat, endLon := 21.0, 105.0, 21.1, 105.0
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		sign     float64
+	}{
+		{name: "east is right", lat: 21.05, lon: 105.001, sign: 1},
+		{name: "west is left", lat: 21.05, lon: 104.999, sign: -1},
+		{name: "on the path", lat: 21.05, lon: 105, sign: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CrossTrackDistance(tt.lat, tt.lon, startLat, startLon, endLat, endLon)
+			if err != nil {
+				t.Fatalf("CrossTrackDistance() error = %v", err)
+			}
+
+			// 0.001 degree of longitude is about 104 m at 21 degree north
+			want := tt.sign * Distance(tt.lat, tt.lon, tt.lat, 105)
+			if math.Abs(got-want) > 0.5 {
+				t.Errorf("CrossTrackDistance() = %.3f, want %.3f", got, want)
+			}
+		})
+	}
+}
+
+func TestAlongTrackDistance(t *testing.T) {
+	startLat, startLon, endLat, endLon := 21.0, 105.0, 21.1, 105.0
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		sign     float64
+	}{
+		{name: "ahead of start", lat: 21.05, lon: 105.001, sign: 1},
+		{name: "behind start", lat: 20.95, lon: 104.999, sign: -1},
+		{name: "past the end", lat: 21.2, lon: 105, sign: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AlongTrackDistance(tt.lat, tt.lon, startLat, startLon, endLat, endLon)
+			if err != nil {
+				t.Fatalf("AlongTrackDistance() error = %v", err)
+			}
+
+			want := tt.sign * Distance(startLat, startLon, tt.lat, startLon)
+			if math.Abs(got-want) > 0.005*math.Abs(want) {
+				t.Errorf("AlongTrackDistance() = %.3f, want %.3f", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package geo
+
+import "math"
+
+const metersPerDegree = 111320.0
+
+type BBox struct {
+	MinLat, MinLon, MaxLat, MaxLon float64
+}
+
+func PointBBox(lat, lon float64) BBox {
+	return BBox{lat, lon, lat, lon}
+}
+
+func (b BBox) Extend(lat, lon float64) BBox {
+	return BBox{
+		MinLat: math.Min(b.MinLat, lat),
+		MinLon: math.Min(b.MinLon, lon),
+		MaxLat: math.Max(b.MaxLat, lat),
+		MaxLon: math.Max(b.MaxLon, lon),
+	}
+}
+
+// Buffer grows the box by the given meters on every side.
+func (b BBox) Buffer(meters float64) BBox {
+	dLat := meters / metersPerDegree
+	cosLat := math.Max(math.Cos(Rad(math.Max(math.Abs(b.MinLat), math.Abs(b.MaxLat)))), 0.01)
+	dLon := meters / (metersPerDegree * cosLat)
+
+	return BBox{b.MinLat - dLat, b.MinLon - dLon, b.MaxLat + dLat, b.MaxLon + dLon}
+}
+
+func (b BBox) Intersects(o BBox) bool {
+	return b.MinLat <= o.MaxLat && o.MinLat <= b.MaxLat && b.MinLon <= o.MaxLon && o.MinLon <= b.MaxLon
+}
+
+type gridCell struct {
+	lat, lon int
+}
+
+// GridIndex is a fixed cell spatial index of bounding boxes keyed by ID. Boxes
+// crossing the antimeridian are not supported. It is not safe for concurrent use.
+type GridIndex struct {
+	cellSize float64 // degree
+	cells    map[gridCell]map[string]struct{}
+	boxes    map[string]BBox
+}
+
+func NewGridIndex(cellSize float64) *GridIndex {
+	return &GridIndex{
+		cellSize: cellSize,
+		cells:    map[gridCell]map[string]struct{}{},
+		boxes:    map[string]BBox{},
+	}
+}
+
+func (g *GridIndex) cellRange(b BBox) (minCell, maxCell gridCell) {
+	minCell = gridCell{int(math.Floor(b.MinLat / g.cellSize)), int(math.Floor(b.MinLon / g.cellSize))}
+	maxCell = gridCell{int(math.Floor(b.MaxLat / g.cellSize)), int(math.Floor(b.MaxLon / g.cellSize))}
+
+	return
+}
+
+// Insert adds the box, replacing any previous box with the same ID.
+func (g *GridIndex) Insert(id string, b BBox) {
+	g.Remove(id)
+
+	minCell, maxCell := g.cellRange(b)
+	for lat := minCell.lat; lat <= maxCell.lat; lat++ {
+		for lon := minCell.lon; lon <= maxCell.lon; lon++ {
+			c := gridCell{lat, lon}
+			if g.cells[c] == nil {
+				g.cells[c] = map[string]struct{}{}
+			}
+			g.cells[c][id] = struct{}{}
+		}
+	}
+	g.boxes[id] = b
+}
+
+func (g *GridIndex) Remove(id string) {
+	b, ok := g.boxes[id]
+	if !ok {
+		return
+	}
+
+	minCell, maxCell := g.cellRange(b)
+	for lat := minCell.lat; lat <= maxCell.lat; lat++ {
+		for lon := minCell.lon; lon <= maxCell.lon; lon++ {
+			c := gridCell{lat, lon}
+			delete(g.cells[c], id)
+			if len(g.cells[c]) == 0 {
+				delete(g.cells, c)
+			}
+		}
+	}
+	delete(g.boxes, id)
+}
+
+// Query returns the IDs whose box intersects b.
+func (g *GridIndex) Query(b BBox) []string {
+	seen := map[string]struct{}{}
+	ids := []string{}
+
+	minCell, maxCell := g.cellRange(b)
+	for lat := minCell.lat; lat <= maxCell.lat; lat++ {
+		for lon := minCell.lon; lon <= maxCell.lon; lon++ {
+			for id := range g.cells[gridCell{lat, lon}] {
+				if _, ok := seen[id]; ok {
+					continue
+				}
+				seen[id] = struct{}{}
+
+				if g.boxes[id].Intersects(b) {
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+
+	return ids
+}
+
+func (g *GridIndex) Len() int {
+	return len(g.boxes)
+}
@@ -0,0 +1,81 @@
+package geo
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+const (
+	benchmarkTracks  = 500
+	benchmarkVolumes = 5000
+)
+
+// benchmarkBoxes scatters volumes of up to 1 km over a square degree, about the
+// area a deployment watches.
+func benchmarkBoxes(r *rand.Rand) map[string]BBox {
+	boxes := make(map[string]BBox, benchmarkVolumes)
+	for i := 0; i < benchmarkVolumes; i++ {
+		lat, lon := 21+r.Float64(), 105+r.Float64()
+		boxes[strconv.Itoa(i)] = PointBBox(lat, lon).Buffer(100 + 400*r.Float64())
+	}
+
+	return boxes
+}
+
+func benchmarkPositions(r *rand.Rand) []BBox {
+	positions := make([]BBox, benchmarkTracks)
+	for i := range positions {
+		positions[i] = PointBBox(21+r.Float64(), 105+r.Float64())
+	}
+
+	return positions
+}
+
+func BenchmarkGridIndexQuery(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	g := NewGridIndex(0.05)
+	for id, box := range benchmarkBoxes(r) {
+		g.Insert(id, box)
+	}
+	positions := benchmarkPositions(r)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, p := range positions {
+			g.Query(p)
+		}
+	}
+}
+
+// BenchmarkBBoxScan is the linear scan the grid index replaces.
+func BenchmarkBBoxScan(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	boxes := benchmarkBoxes(r)
+	positions := benchmarkPositions(r)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, p := range positions {
+			ids := []string{}
+			for id, box := range boxes {
+				if box.Intersects(p) {
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkGridIndexInsert(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	boxes := benchmarkBoxes(r)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		g := NewGridIndex(0.05)
+		for id, box := range boxes {
+			g.Insert(id, box)
+		}
+	}
+}
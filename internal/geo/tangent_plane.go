@@ -0,0 +1,50 @@
+package geo
+
+import "math"
+
+// LocalTangentPlane is an east, north, up frame anchored at an origin. The origin
+// ECEF position and rotation terms are computed once and reused for every conversion.
+type LocalTangentPlane struct {
+	Lat, Lon, Alt float64
+
+	x0, y0, z0     float64
+	sinLat, cosLat float64
+	sinLon, cosLon float64
+}
+
+func NewLocalTangentPlane(lat, lon, alt float64) *LocalTangentPlane {
+	p := &LocalTangentPlane{Lat: lat, Lon: lon, Alt: alt}
+	p.x0, p.y0, p.z0 = GeodeticToECEF(lat, lon, alt)
+	p.sinLat, p.cosLat = math.Sincos(Rad(lat))
+	p.sinLon, p.cosLon = math.Sincos(Rad(lon))
+
+	return p
+}
+
+func (p *LocalTangentPlane) ECEFToENU(x, y, z float64) Vec {
+	dx, dy, dz := x-p.x0, y-p.y0, z-p.z0
+
+	return Vec{
+		X: -p.sinLon*dx + p.cosLon*dy,
+		Y: -p.sinLat*p.cosLon*dx - p.sinLat*p.sinLon*dy + p.cosLat*dz,
+		Z: p.cosLat*p.cosLon*dx + p.cosLat*p.sinLon*dy + p.sinLat*dz,
+	}
+}
+
+func (p *LocalTangentPlane) ENUToECEF(v Vec) (x, y, z float64) {
+	x = p.x0 - p.sinLon*v.X - p.sinLat*p.cosLon*v.Y + p.cosLat*p.cosLon*v.Z
+	y = p.y0 + p.cosLon*v.X - p.sinLat*p.sinLon*v.Y + p.cosLat*p.sinLon*v.Z
+	z = p.z0 + p.cosLat*v.Y + p.sinLat*v.Z
+
+	return
+}
+
+// ToENU converts a geodetic position to east, north, up meters from the origin.
+func (p *LocalTangentPlane) ToENU(lat, lon, alt float64) Vec {
+	return p.ECEFToENU(GeodeticToECEF(lat, lon, alt))
+}
+
+// ToGeodetic converts east, north, up meters from the origin to a geodetic position.
+func (p *LocalTangentPlane) ToGeodetic(v Vec) (lat, lon, alt float64) {
+	return ECEFToGeodetic(p.ENUToECEF(v))
+}
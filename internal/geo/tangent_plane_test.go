@@ -0,0 +1,76 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLocalTangentPlaneRoundTrip(t *testing.T) {
+	tests := []struct {
+		name          string
+		lat, lon, alt float64 // origin
+		enu           Vec
+	}{
+		{name: "origin", lat: 21.0285, lon: 105.8542, alt: 10, enu: Vec{}},
+		{name: "kilometers away", lat: 21.0285, lon: 105.8542, alt: 10, enu: Vec{X: 3500, Y: -1200, Z: 150}},
+		{name: "southern hemisphere", lat: -37.95, lon: 144.42, alt: 0, enu: Vec{X: -800, Y: 2500, Z: 60}},
+		{name: "across the antimeridian", lat: 52.0, lon: 179.999, alt: 100, enu: Vec{X: 1000, Y: 10, Z: -50}},
+		{name: "near the pole", lat: 89.9, lon: 0, alt: 0, enu: Vec{X: 200, Y: 200, Z: 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewLocalTangentPlane(tt.lat, tt.lon, tt.alt)
+
+			lat, lon, alt := p.ToGeodetic(tt.enu)
+			got := p.ToENU(lat, lon, alt)
+			if got.Sub(tt.enu).Norm() > 1e-6 {
+				t.Errorf("ToENU(ToGeodetic(%v)) = %v", tt.enu, got)
+			}
+		})
+	}
+}
+
+func TestLocalTangentPlaneAxes(t *testing.T) {
+	p := NewLocalTangentPlane(21.0285, 105.8542, 10)
+
+	tests := []struct {
+		name          string
+		lat, lon, alt float64
+		want          func(v Vec) bool
+	}{
+		{name: "north is +Y", lat: 21.0385, lon: 105.8542, alt: 10, want: func(v Vec) bool { return v.Y > 1000 && math.Abs(v.X) < 1e-6 }},
+		{name: "east is +X", lat: 21.0285, lon: 105.8642, alt: 10, want: func(v Vec) bool { return v.X > 1000 && math.Abs(v.Y) < 1 }},
+		{name: "up is +Z", lat: 21.0285, lon: 105.8542, alt: 110, want: func(v Vec) bool { return math.Abs(v.Z-100) < 1e-6 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if v := p.ToENU(tt.lat, tt.lon, tt.alt); !tt.want(v) {
+				t.Errorf("ToENU() = %v", v)
+			}
+		})
+	}
+}
+
+func TestECEFRoundTrip(t *testing.T) {
+	tests := []struct {
+		name          string
+		lat, lon, alt float64
+	}{
+		{name: "equator", lat: 0, lon: 0, alt: 0},
+		{name: "hanoi", lat: 21.0285, lon: 105.8542, alt: 25},
+		{name: "south west", lat: -33.9, lon: -70.6, alt: 5000},
+		{name: "pole", lat: 90, lon: 0, alt: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lat, lon, alt := ECEFToGeodetic(GeodeticToECEF(tt.lat, tt.lon, tt.alt))
+
+			if math.Abs(lat-tt.lat) > 1e-9 || math.Abs(alt-tt.alt) > 1e-6 {
+				t.Errorf("ECEFToGeodetic(GeodeticToECEF()) = %v, %v, %v", lat, lon, alt)
+			}
+			if tt.lat != 90 && math.Abs(lon-tt.lon) > 1e-9 {
+				t.Errorf("longitude = %v, want %v", lon, tt.lon)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package geo
+
+import "math"
+
+type Vec struct {
+	X, Y, Z float64
+}
+
+func (a Vec) Add(b Vec) Vec       { return Vec{a.X + b.X, a.Y + b.Y, a.Z + b.Z} }
+func (a Vec) Sub(b Vec) Vec       { return Vec{a.X - b.X, a.Y - b.Y, a.Z - b.Z} }
+func (a Vec) Scale(k float64) Vec { return Vec{a.X * k, a.Y * k, a.Z * k} }
+func (a Vec) Dot(b Vec) float64   { return a.X*b.X + a.Y*b.Y + a.Z*b.Z }
+func (a Vec) Norm() float64       { return math.Sqrt(a.Dot(a)) }
+func (a Vec) Horizontal() Vec     { return Vec{a.X, a.Y, 0} }
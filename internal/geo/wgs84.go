@@ -0,0 +1,49 @@
+// Package geo holds the WGS84 geodesy shared by containment, ETA and track history analytics.
+//
+// Latitudes and longitudes are in degree, altitudes and distances in meter and
+// azimuths in radian clockwise from north.
+package geo
+
+import "math"
+
+const (
+	A          = 6378137.0         // WGS84 semi-major axis in meter
+	F          = 1 / 298.257223563 // WGS84 flattening
+	B          = A * (1 - F)       // WGS84 semi-minor axis in meter
+	E2         = F * (2 - F)       // first eccentricity squared
+	EP2        = E2 / (1 - E2)     // second eccentricity squared
+	MeanRadius = 6371008.8         // mean earth radius in meter
+)
+
+func Rad(deg float64) float64 { return deg * math.Pi / 180 }
+func Deg(rad float64) float64 { return rad * 180 / math.Pi }
+
+// GeodeticToECEF converts a geodetic position to earth-centered earth-fixed meters.
+func GeodeticToECEF(lat, lon, alt float64) (x, y, z float64) {
+	sinLat, cosLat := math.Sincos(Rad(lat))
+	sinLon, cosLon := math.Sincos(Rad(lon))
+
+	N := A / math.Sqrt(1-E2*sinLat*sinLat)
+
+	x = (N + alt) * cosLat * cosLon
+	y = (N + alt) * cosLat * sinLon
+	z = (N*(1-E2) + alt) * sinLat
+
+	return
+}
+
+// ECEFToGeodetic converts earth-centered earth-fixed meters back to a geodetic
+// position with Bowring's formula, accurate to the millimeter near the surface.
+func ECEFToGeodetic(x, y, z float64) (lat, lon, alt float64) {
+	p := math.Hypot(x, y)
+	theta := math.Atan2(z*A, p*B)
+	sinTheta, cosTheta := math.Sincos(theta)
+
+	latR := math.Atan2(z+EP2*B*sinTheta*sinTheta*sinTheta, p-E2*A*cosTheta*cosTheta*cosTheta)
+	sinLat, cosLat := math.Sincos(latR)
+
+	N := A / math.Sqrt(1-E2*sinLat*sinLat)
+	alt = p*cosLat + z*sinLat - A*A/N
+
+	return Deg(latR), Deg(math.Atan2(y, x)), alt
+}
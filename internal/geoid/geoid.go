@@ -0,0 +1,109 @@
+// Package geoid converts between heights above the WGS84 ellipsoid and above the mean
+// sea level with an EGM96 or EGM2008 geoid grid read from a local file.
+//
+// Latitudes and longitudes are in degree and heights in meter. The undulation N is the
+// height of the geoid above the ellipsoid, h = H + N.
+package geoid
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Model is a global grid of geoid undulations, rows from north to south.
+type Model struct {
+	name  string
+	north float64
+	west  float64
+	dLat  float64
+	dLon  float64
+	rows  int
+	cols  int
+	wrap  bool // the last column is followed by the first one, 360 degree east
+	data  []float32
+}
+
+// Load reads a GeographicLib .pgm geoid such as egm96-5.pgm or egm2008-1.pgm, or an NGA
+// ASCII grid such as WW15MGH.GRD.
+func Load(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m *Model
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pgm":
+		m, err = readPGM(data)
+	case ".grd":
+		m, err = readGRD(data)
+	default:
+		err = fmt.Errorf("unsupported geoid file, want .pgm or .grd")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("geoid %s: %w", filepath.Base(path), err)
+	}
+
+	m.name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	return m, nil
+}
+
+// Name returns the name of the geoid file, empty without a model.
+func (m *Model) Name() string {
+	if m == nil {
+		return ""
+	}
+
+	return m.name
+}
+
+// Undulation interpolates bilinearly the height of the geoid above the ellipsoid. It is
+// 0 without a model, mean sea level heights then being taken as ellipsoid ones.
+func (m *Model) Undulation(lat, lon float64) float64 {
+	if m == nil {
+		return 0
+	}
+
+	y := (m.north - math.Max(-90, math.Min(90, lat))) / m.dLat
+	x := math.Mod(lon-m.west, 360)
+	if x < 0 {
+		x += 360
+	}
+	x /= m.dLon
+
+	y = math.Max(0, math.Min(float64(m.rows-1), y))
+	if !m.wrap {
+		x = math.Min(float64(m.cols-1), x)
+	}
+
+	r0, c0 := int(y), int(x)
+	r1, c1 := min(r0+1, m.rows-1), c0+1
+	if c0 >= m.cols {
+		c0 = 0
+	}
+	if c1 >= m.cols {
+		c1 = 0
+		if !m.wrap {
+			c1 = m.cols - 1
+		}
+	}
+	fy, fx := y-float64(int(y)), x-float64(int(x))
+
+	at := func(r, c int) float64 { return float64(m.data[r*m.cols+c]) }
+
+	return (1-fy)*((1-fx)*at(r0, c0)+fx*at(r0, c1)) + fy*((1-fx)*at(r1, c0)+fx*at(r1, c1))
+}
+
+// EllipsoidHeight converts a height above the mean sea level to the WGS84 ellipsoid.
+func (m *Model) EllipsoidHeight(lat, lon, msl float64) float64 {
+	return msl + m.Undulation(lat, lon)
+}
+
+// MSLHeight converts a height above the WGS84 ellipsoid to the mean sea level.
+func (m *Model) MSLHeight(lat, lon, ellipsoid float64) float64 {
+	return ellipsoid - m.Undulation(lat, lon)
+}
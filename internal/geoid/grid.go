@@ -0,0 +1,148 @@
+package geoid
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// readPGM loads a GeographicLib geoid, 16-bit samples scaled by the Offset and Scale
+// comments of the header. Rows go from 90 to -90 degree and columns from 0 degree east
+// around the globe.
+func readPGM(data []byte) (*Model, error) {
+	offset, scale := math.NaN(), math.NaN()
+	tokens := []string{}
+
+	pos := 0
+	for len(tokens) < 4 {
+		for pos < len(data) && isSpace(data[pos]) {
+			pos++
+		}
+		if pos >= len(data) {
+			return nil, fmt.Errorf("truncated PGM header")
+		}
+
+		if data[pos] == '#' {
+			end := bytes.IndexByte(data[pos:], '\n')
+			if end < 0 {
+				return nil, fmt.Errorf("truncated PGM header")
+			}
+
+			fields := strings.Fields(string(data[pos+1 : pos+end]))
+			if len(fields) == 2 {
+				switch fields[0] {
+				case "Offset":
+					offset, _ = strconv.ParseFloat(fields[1], 64)
+				case "Scale":
+					scale, _ = strconv.ParseFloat(fields[1], 64)
+				}
+			}
+			pos += end
+
+			continue
+		}
+
+		start := pos
+		for pos < len(data) && !isSpace(data[pos]) {
+			pos++
+		}
+		tokens = append(tokens, string(data[start:pos]))
+	}
+	// a single whitespace ends the header
+	pos++
+
+	if tokens[0] != "P5" {
+		return nil, fmt.Errorf("not a binary PGM file")
+	}
+	if math.IsNaN(offset) || math.IsNaN(scale) {
+		return nil, fmt.Errorf("PGM header has no Offset and Scale")
+	}
+
+	cols, errW := strconv.Atoi(tokens[1])
+	rows, errH := strconv.Atoi(tokens[2])
+	maxVal, errM := strconv.Atoi(tokens[3])
+	if errW != nil || errH != nil || errM != nil || cols < 2 || rows < 2 {
+		return nil, fmt.Errorf("invalid PGM size %s x %s", tokens[1], tokens[2])
+	}
+	if maxVal < 256 {
+		return nil, fmt.Errorf("PGM has 8-bit samples, want 16-bit")
+	}
+	if len(data)-pos < rows*cols*2 {
+		return nil, fmt.Errorf("PGM has %d bytes of samples, want %d", len(data)-pos, rows*cols*2)
+	}
+
+	m := &Model{
+		north: 90,
+		west:  0,
+		dLat:  180 / float64(rows-1),
+		dLon:  360 / float64(cols),
+		rows:  rows,
+		cols:  cols,
+		wrap:  true,
+		data:  make([]float32, rows*cols),
+	}
+	for i := range m.data {
+		m.data[i] = float32(offset + scale*float64(binary.BigEndian.Uint16(data[pos+2*i:])))
+	}
+
+	return m, nil
+}
+
+// readGRD loads an NGA ASCII grid, a header of south, north, west and east bounds and
+// spacings followed by the undulations from north to south, west to east.
+func readGRD(data []byte) (*Model, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Split(bufio.ScanWords)
+
+	values := []float64{}
+	for scanner.Scan() {
+		v, err := strconv.ParseFloat(scanner.Text(), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid grid value %q", scanner.Text())
+		}
+		values = append(values, v)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(values) < 6 {
+		return nil, fmt.Errorf("truncated grid header")
+	}
+
+	south, north, west, east, dLat, dLon := values[0], values[1], values[2], values[3], values[4], values[5]
+	if dLat <= 0 || dLon <= 0 || north <= south || east <= west {
+		return nil, fmt.Errorf("invalid grid header %v", values[:6])
+	}
+	if north-south < 180 || east-west < 360 {
+		return nil, fmt.Errorf("grid covers %v to %v, %v to %v, want the globe", south, north, west, east)
+	}
+
+	rows := int(math.Round((north-south)/dLat)) + 1
+	cols := int(math.Round((east-west)/dLon)) + 1
+	if len(values)-6 != rows*cols {
+		return nil, fmt.Errorf("grid has %d values, want %d x %d", len(values)-6, rows, cols)
+	}
+
+	m := &Model{
+		north: north,
+		west:  west,
+		dLat:  dLat,
+		dLon:  dLon,
+		rows:  rows,
+		cols:  cols,
+		data:  make([]float32, rows*cols),
+	}
+	for i, v := range values[6:] {
+		m.data[i] = float32(v)
+	}
+
+	return m, nil
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
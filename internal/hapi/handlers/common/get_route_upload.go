@@ -0,0 +1,102 @@
+package common
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	route "172.21.5.249/air-trans/at-drone/internal/route"
+	service "172.21.5.249/air-trans/at-drone/internal/service"
+	types "172.21.5.249/air-trans/at-drone/internal/types"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetRouteUpload reads an uploaded route from the multipart field "file", or from the
+// request body, with the format, altitude_reference and home_altitude query params.
+func GetRouteUpload(c echo.Context) ([]byte, service.RouteImportOptions, error) {
+	opt := service.RouteImportOptions{}
+
+	var format, reference string
+	var homeAltitude float64
+	err := echo.QueryParamsBinder(c).
+		String("format", &format).
+		String("altitude_reference", &reference).
+		Float64("home_altitude", &homeAltitude).
+		BindError()
+	if err != nil {
+		return nil, opt, err
+	}
+
+	opt.Format = route.Format(strings.ToLower(format))
+	opt.Reference, err = route.ParseAltitudeReference(reference)
+	if err != nil {
+		return nil, opt, err
+	}
+	if c.QueryParam("home_altitude") != "" {
+		opt.HomeAltitude = &homeAltitude
+	}
+
+	var body io.Reader = c.Request().Body
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return nil, opt, err
+		}
+
+		src, err := file.Open()
+		if err != nil {
+			return nil, opt, err
+		}
+		defer src.Close()
+
+		opt.FileName = file.Filename
+		body = src
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, opt, err
+	}
+
+	return data, opt, nil
+}
+
+// RouteImportError answers with the located issues when the uploaded route is invalid.
+func RouteImportError(c echo.Context, err error) error {
+	var parseErr *route.ParseError
+	if errors.As(err, &parseErr) {
+		return c.JSON(http.StatusBadRequest, types.ValidationErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Errors:  parseErr.Issues,
+		})
+	}
+
+	return CorridorError(c, err)
+}
+
+// CorridorError answers with the findings when the corridor route is rejected, and with
+// a conflict when another update changed the corridor first.
+func CorridorError(c echo.Context, err error) error {
+	var validationErr *service.CorridorValidationError
+	if errors.As(err, &validationErr) {
+		return c.JSON(http.StatusBadRequest, types.ValidationErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+			Errors:  validationErr.Findings,
+		})
+	}
+	if errors.Is(err, service.ErrCorridorVersion) {
+		return c.JSON(http.StatusConflict, types.ErrorResponse{
+			Code:    http.StatusConflict,
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: err.Error(),
+	})
+}
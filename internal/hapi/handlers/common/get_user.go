@@ -0,0 +1,32 @@
+package common
+
+import (
+	"errors"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+var userClaims = []string{"username", "preferred_username", "sub"}
+
+var ErrNoUser = errors.New("no user in the token")
+
+// GetUser returns the acting user from the JWT validated by echojwt. The X-Username
+// header is only trusted when JWT validation is disabled.
+func GetUser(c echo.Context, validateJWT bool) (string, error) {
+	if token, ok := c.Get("user").(*jwt.Token); ok {
+		if claims, ok := token.Claims.(jwt.MapClaims); ok {
+			for _, key := range userClaims {
+				if user, ok := claims[key].(string); ok && user != "" {
+					return user, nil
+				}
+			}
+		}
+	}
+
+	if validateJWT {
+		return "", ErrNoUser
+	}
+
+	return c.Request().Header.Get("X-Username"), nil
+}
@@ -0,0 +1,22 @@
+package common
+
+import (
+	"net/http"
+
+	export "172.21.5.249/air-trans/at-drone/internal/export"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetExportFormat negotiates GeoJSON or KML from the format query param, then from
+// the Accept header.
+func GetExportFormat(c echo.Context) (export.Format, error) {
+	return export.Negotiate(c.QueryParam("format"), c.Request().Header.Get(echo.HeaderAccept))
+}
+
+func WriteMapExport(c echo.Context, doc *export.Document, format export.Format) error {
+	c.Response().Header().Set(echo.HeaderContentType, format.ContentType())
+	c.Response().WriteHeader(http.StatusOK)
+
+	return doc.Write(c.Response(), format)
+}
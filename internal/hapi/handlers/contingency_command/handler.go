@@ -0,0 +1,53 @@
+package command
+
+import (
+	"net/http"
+	"strconv"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	hapi "172.21.5.249/air-trans/at-drone/internal/hapi"
+
+	queryoptions "go.jtlabs.io/query"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func SearchRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/contingency-commands/search", searchHandler(s))
+}
+
+// Search contingency command godoc
+//
+//	@Summary		Search contingency command
+//	@Description	Search contingency command use Query option https://github.com/jtlabsio/mongo/
+//	@Tags			contingency-commands
+//	@Accept			json
+//	@Produce		json
+//	@Param			page[page]	query		int	true	"page number"
+//	@Param			page[size]	query		int	true	"page size"
+//	@Success		200			{object}	pb.ContingencyCommand
+//	@Failure		400			{object}	types.ErrorResponse
+//	@Router			/contingency-commands/search [get]
+func searchHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		opt, err := queryoptions.FromQuerystring(c.Request().URL.RequestURI())
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to get query option from string: %s", c.Request().URL.RequestURI())
+		}
+
+		config.PrintDebugLog(ctx, "Search contingency command: %+v", opt)
+
+		result, count := s.MainService.SearchContingencyCommand(ctx, opt)
+
+		config.PrintDebugLog(ctx, "Search contingency command result: %d", count)
+
+		c.Response().Header().Set("x-total-count", strconv.FormatInt(count, 10))
+		return c.JSON(http.StatusOK, *result)
+	}
+}
@@ -0,0 +1,588 @@
+package corridor
+
+import (
+	"net/http"
+	"strconv"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	hapi "172.21.5.249/air-trans/at-drone/internal/hapi"
+	common "172.21.5.249/air-trans/at-drone/internal/hapi/handlers/common"
+	types "172.21.5.249/air-trans/at-drone/internal/types"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	queryoptions "go.jtlabs.io/query"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func CreateRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.POST("/corridors", createHandler(s))
+}
+
+// Create corridor godoc
+//
+//	@Summary		Create a corridor
+//	@Description	Create a new corridor
+//	@Tags			corridors
+//	@Accept			json
+//	@Produce		json
+//	@Param			corridor	body		pb.ContainmentCorridor	true	"corridor body"
+//	@Param			eventAPI	query		bool					true	"event api call flag"
+//	@Success		200			{object}	pb.ContainmentCorridor
+//	@Failure		400			{object}	types.ErrorResponse
+//	@Router			/corridors [post]
+func createHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		u := &pb.ContainmentCorridor{}
+		if err := c.Bind(u); err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to bind data")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		eventAPI, _ := strconv.ParseBool(c.QueryParam("eventAPI"))
+		if !eventAPI {
+			u.ID = requestID
+		}
+
+		config.PrintDebugLog(ctx, "Create corridor: %+v", u)
+
+		_, err := s.MainService.CreateCorridor(ctx, u, eventAPI)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to create corridor: %+v", u)
+
+			return common.CorridorError(c, err)
+		}
+
+		return c.JSON(http.StatusCreated, u)
+	}
+}
+
+func DeleteByIDRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.DELETE("/corridors/:id", deleteByIDHandler(s))
+}
+
+// Delete corridor by ID godoc
+//
+//	@Summary		Delete corridor by ID
+//	@Description	Delete corridor by ID
+//	@Tags			corridors
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		string	true	"corridor id"
+//	@Param			eventAPI	query		bool	true	"event api call flag"
+//	@Success		200			{object}	pb.ContainmentCorridor
+//	@Failure		400			{object}	types.ErrorResponse
+//	@Router			/corridors/{id} [delete]
+func deleteByIDHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		id := c.Param("id")
+		eventAPI, _ := strconv.ParseBool(c.QueryParam("eventAPI"))
+
+		config.PrintDebugLog(ctx, "Delete corridor by id: %s", id)
+
+		err := s.MainService.DeleteCorridorByID(ctx, id, eventAPI)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to delete corridor by id: %s", id)
+
+			return c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, types.SucceedResponse{
+			Success: true,
+		})
+	}
+}
+
+func PatchByIDRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.PATCH("/corridors/:id", patchByIDHandler(s))
+}
+
+// Patch corridor by ID godoc
+//
+//	@Summary		Patch corridor by ID
+//	@Description	Patch corridor by ID use standard of JSON PATCH https://jsonpatch.com/
+//	@Tags			corridors
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		string			true	"corridor id"
+//	@Param			corridor	body		jsonpatch.Patch	true	"Patch operation format Array of Operation Add, Remove, Replace, Copy, Move, Test. Get example at https://jsonpatch.com/"
+//	@Param			eventAPI	query		bool			true	"event api call flag"
+//	@Success		200			{object}	pb.PatchResponse
+//	@Failure		400			{object}	types.ErrorResponse
+//	@Failure		409			{object}	types.ErrorResponse
+//	@Router			/corridors/{id} [patch]
+func patchByIDHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		patch := &jsonpatch.Patch{}
+		if err := c.Bind(patch); err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to bind data")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		id := c.Param("id")
+		eventAPI, _ := strconv.ParseBool(c.QueryParam("eventAPI"))
+
+		config.PrintDebugLog(ctx, "Patch corridor by id: %s: %+v", id, patch)
+
+		result, err := s.MainService.PatchCorridorByID(ctx, patch, id, eventAPI)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to patch corridor by id: %s: %+v", id, patch)
+
+			return common.CorridorError(c, err)
+		}
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+func SearchRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/corridors/search", searchHandler(s))
+}
+
+// Search corridor godoc
+//
+//	@Summary		Search corridor
+//	@Description	Search corridor use Query option https://github.com/jtlabsio/mongo/
+//	@Tags			corridors
+//	@Accept			json
+//	@Produce		json
+//	@Param			page[page]	query		int	true	"page number"
+//	@Param			page[size]	query		int	true	"page size"
+//	@Success		200			{object}	pb.ContainmentCorridor
+//	@Failure		400			{object}	types.ErrorResponse
+//	@Router			/corridors/search [get]
+func searchHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		opt, err := queryoptions.FromQuerystring(c.Request().URL.RequestURI())
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to get query option from string: %s", c.Request().URL.RequestURI())
+		}
+
+		config.PrintDebugLog(ctx, "Search corridor: %+v", opt)
+
+		result, count := s.MainService.SearchCorridor(ctx, opt)
+
+		config.PrintDebugLog(ctx, "Search corridor result: %d", count)
+
+		c.Response().Header().Set("x-total-count", strconv.FormatInt(count, 10))
+		return c.JSON(http.StatusOK, *result)
+	}
+}
+
+func FindByIDRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/corridors/:id", findByIDHandler(s))
+}
+
+// Find corridor by ID godoc
+//
+//	@Summary		Find corridor by ID
+//	@Description	Find corridor by ID
+//	@Tags			corridors
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string	true	"corridor id"
+//	@Success		200	{object}	pb.ContainmentCorridor
+//	@Failure		400	{object}	types.ErrorResponse
+//	@Router			/corridors/{id} [get]
+func findByIDHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := c.Param("id")
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		config.PrintDebugLog(ctx, "Find corridor by id: %s", id)
+
+		u, err := s.MainService.FindCorridorByID(ctx, id)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to find corridor by id: %s", id)
+
+			return c.JSON(http.StatusNotFound, types.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusOK, u)
+	}
+}
+
+func FindAllRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/corridors", findAllHandler(s))
+}
+
+// Find all corridor godoc
+//
+//	@Summary		Find all corridor
+//	@Description	Find all corridor
+//	@Tags			corridors
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	pb.ContainmentCorridor
+//	@Failure		400	{object}	types.ErrorResponse
+//	@Router			/corridors [get]
+func findAllHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		config.PrintDebugLog(ctx, "Find corridor all")
+
+		u, err := s.MainService.FindCorridorAll(ctx)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to find corridor all")
+
+			return c.JSON(http.StatusNotFound, types.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, u)
+	}
+}
+
+func UpdateByIDRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.PUT("/corridors/:id", updateByIDHandler(s))
+}
+
+// Update corridor by ID godoc
+//
+//	@Summary		Update corridor by ID
+//	@Description	Update corridor by ID
+//	@Tags			corridors
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		string					true	"corridor id"
+//	@Param			corridor	body		pb.ContainmentCorridor	true	"corridor body"
+//	@Param			eventAPI	query		bool					true	"event api call flag"
+//	@Success		200			{object}	pb.ContainmentCorridor
+//	@Failure		400			{object}	types.ErrorResponse
+//	@Failure		409			{object}	types.ErrorResponse
+//	@Router			/corridors/{id} [put]
+func updateByIDHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		u := &pb.ContainmentCorridor{}
+		if err := c.Bind(u); err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to bind data")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		id := c.Param("id")
+		eventAPI, _ := strconv.ParseBool(c.QueryParam("eventAPI"))
+
+		config.PrintDebugLog(ctx, "Update corridor by id: %s: %+v", id, u)
+
+		result, err := s.MainService.UpdateCorridorByID(ctx, u, id, eventAPI)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to update corridor by id: %s: %+v", id, u)
+
+			return common.CorridorError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+func ImportRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.POST("/corridors/import", importHandler(s))
+}
+
+// Import corridor godoc
+//
+//	@Summary		Import a corridor
+//	@Description	Create a corridor from a QGroundControl .plan, QGC WPL 110, KML or GeoJSON route, uploaded as the multipart field file or as the body
+//	@Tags			corridors
+//	@Accept			mpfd,json,plain,xml
+//	@Produce		json
+//	@Param			file				formData	file	false	"route file"
+//	@Param			format				query		string	false	"plan, wpl, kml or geojson, detected when empty"
+//	@Param			altitude_reference	query		string	false	"AMSL, RELATIVE or TERRAIN for the altitudes the format leaves unqualified, default AMSL"
+//	@Param			home_altitude		query		number	false	"home altitude in meter AMSL, default the home position of the file"
+//	@Param			name				query		string	false	"corridor name"
+//	@Param			drone_id			query		string	false	"drone id"
+//	@Param			order_id			query		string	false	"order id"
+//	@Param			lateral_tolerance	query		number	false	"lateral tolerance in meter"
+//	@Param			vertical_tolerance	query		number	false	"vertical tolerance in meter"
+//	@Param			valid_from			query		int		false	"start of validity in millisecond"
+//	@Param			valid_to			query		int		false	"end of validity in millisecond"
+//	@Success		201					{object}	pb.ContainmentCorridor
+//	@Failure		400					{object}	types.ValidationErrorResponse
+//	@Router			/corridors/import [post]
+func importHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		u := &pb.ContainmentCorridor{ID: requestID}
+		err := echo.QueryParamsBinder(c).
+			String("name", &u.Name).
+			String("drone_id", &u.DroneID).
+			String("order_id", &u.OrderID).
+			Float64("lateral_tolerance", &u.LateralTolerance).
+			Float64("vertical_tolerance", &u.VerticalTolerance).
+			Uint64("valid_from", &u.ValidFrom).
+			Uint64("valid_to", &u.ValidTo).
+			BindError()
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to bind query params")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		data, opt, err := common.GetRouteUpload(c)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to read route upload")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		config.PrintDebugLog(ctx, "Import corridor from %s: %+v", opt.FileName, u)
+
+		result, err := s.MainService.ImportCorridor(ctx, u, data, opt)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to import corridor from %s", opt.FileName)
+
+			return common.RouteImportError(c, err)
+		}
+
+		return c.JSON(http.StatusCreated, result)
+	}
+}
+
+func ExportByIDRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/corridors/:id/export", exportByIDHandler(s))
+}
+
+// Export corridor by ID godoc
+//
+//	@Summary		Export corridor by ID
+//	@Description	Export the corridor as one 3D buffered polygon per segment, in GeoJSON or KML from the Accept header
+//	@Tags			corridors
+//	@Accept			json
+//	@Produce		application/geo+json,application/vnd.google-earth.kml+xml
+//	@Param			id		path	string	true	"corridor id"
+//	@Param			format	query	string	false	"geojson or kml, overrides the Accept header"
+//	@Success		200
+//	@Failure		400	{object}	types.ErrorResponse
+//	@Failure		404	{object}	types.ErrorResponse
+//	@Router			/corridors/{id}/export [get]
+func exportByIDHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := c.Param("id")
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		format, err := common.GetExportFormat(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		config.PrintDebugLog(ctx, "Export corridor by id: %s - format: %s", id, format)
+
+		doc, err := s.MainService.ExportCorridor(ctx, id)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to export corridor by id: %s", id)
+
+			return c.JSON(http.StatusNotFound, types.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: err.Error(),
+			})
+		}
+
+		return common.WriteMapExport(c, doc, format)
+	}
+}
+
+func ValidateRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.POST("/corridors/validate", validateHandler(s))
+}
+
+// Validate corridor godoc
+//
+//	@Summary		Validate a corridor
+//	@Description	Check the corridor route, or the flight route of its order when it has no waypoint, for zero-length or duplicate segments, sharp turns, steep climbs, keep-out geofence crossings and the drone endurance
+//	@Tags			corridors
+//	@Accept			json
+//	@Produce		json
+//	@Param			corridor	body		pb.ContainmentCorridor	true	"corridor body"
+//	@Success		200			{object}	service.RouteValidation
+//	@Failure		400			{object}	types.ErrorResponse
+//	@Router			/corridors/validate [post]
+func validateHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		u := &pb.ContainmentCorridor{}
+		if err := c.Bind(u); err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to bind data")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		config.PrintDebugLog(ctx, "Validate corridor: %+v", u)
+
+		result, err := s.MainService.ValidateCorridor(ctx, u)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to validate corridor: %+v", u)
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+func AmendByIDRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.POST("/corridors/:id/amend", amendByIDHandler(s))
+}
+
+// Amend corridor by ID godoc
+//
+//	@Summary		Amend corridor by ID
+//	@Description	Re-plan an active corridor as a new immutable version taking over at effective_at, now when unset, the user comes from the JWT
+//	@Tags			corridors
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		string					true	"corridor id"
+//	@Param			corridor	body		pb.ContainmentCorridor	true	"amended corridor body"
+//	@Param			eventAPI	query		bool					true	"event api call flag"
+//	@Success		200			{object}	pb.ContainmentCorridor
+//	@Failure		400			{object}	types.ErrorResponse
+//	@Failure		401			{object}	types.ErrorResponse
+//	@Failure		409			{object}	types.ErrorResponse
+//	@Router			/corridors/{id}/amend [post]
+func amendByIDHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		u := &pb.ContainmentCorridor{}
+		if err := c.Bind(u); err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to bind data")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		id := c.Param("id")
+		user, err := common.GetUser(c, s.Config.JWTTokenConfig.ValidateJwt)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to get user")
+
+			return c.JSON(http.StatusUnauthorized, types.ErrorResponse{
+				Code:    http.StatusUnauthorized,
+				Message: err.Error(),
+			})
+		}
+		eventAPI, _ := strconv.ParseBool(c.QueryParam("eventAPI"))
+
+		config.PrintDebugLog(ctx, "Amend corridor by id: %s - user: %s: %+v", id, user, u)
+
+		result, err := s.MainService.AmendCorridor(ctx, u, id, user, eventAPI)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to amend corridor by id: %s: %+v", id, u)
+
+			return common.CorridorError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+func FindVersionsByIDRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/corridors/:id/versions", findVersionsByIDHandler(s))
+}
+
+// Find corridor versions by ID godoc
+//
+//	@Summary		Find corridor versions by ID
+//	@Description	List the immutable versions of the corridor with their effective time
+//	@Tags			corridors
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string	true	"corridor id"
+//	@Success		200	{array}		pb.ContainmentCorridorVersion
+//	@Failure		400	{object}	types.ErrorResponse
+//	@Router			/corridors/{id}/versions [get]
+func findVersionsByIDHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := c.Param("id")
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		config.PrintDebugLog(ctx, "Find versions of corridor by id: %s", id)
+
+		result, err := s.MainService.FindCorridorVersions(ctx, id)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to find versions of corridor by id: %s", id)
+
+			return c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, result)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	config "172.21.5.249/air-trans/at-drone/internal/config"
 	hapi "172.21.5.249/air-trans/at-drone/internal/hapi"
+	common "172.21.5.249/air-trans/at-drone/internal/hapi/handlers/common"
 	types "172.21.5.249/air-trans/at-drone/internal/types"
 	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
 
@@ -239,6 +240,43 @@ func findByIDHandler(s *hapi.Server) echo.HandlerFunc {
 	}
 }
 
+func FindProgressByIDRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/drones/:id/progress", findProgressByIDHandler(s))
+}
+
+// Find drone route progress by ID godoc
+//
+//	@Summary		Find drone route progress by ID
+//	@Description	Find active segment, distance flown and remaining, percent complete and ETA of a tracked drone
+//	@Tags			drones
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string	true	"drone id"
+//	@Success		200	{object}	service.RouteProgress
+//	@Failure		400	{object}	types.ErrorResponse
+//	@Router			/drones/{id}/progress [get]
+func findProgressByIDHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := c.Param("id")
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		config.PrintDebugLog(ctx, "Find drone progress by id: %s", id)
+
+		u, err := s.MainService.FindRouteProgressByDroneID(ctx, id)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to find drone progress by id: %s", id)
+
+			return c.JSON(http.StatusNotFound, types.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusOK, u)
+	}
+}
+
 func FindAllRoute(s *hapi.Server) *echo.Route {
 	return s.Router.Root.GET("/drones", findAllHandler(s))
 }
@@ -326,3 +364,66 @@ func updateByIDHandler(s *hapi.Server) echo.HandlerFunc {
 		return c.JSON(http.StatusOK, result)
 	}
 }
+
+func ExportTrackByIDRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/drones/:id/track/export", exportTrackByIDHandler(s))
+}
+
+// Export drone track by ID godoc
+//
+//	@Summary		Export drone track by ID
+//	@Description	Export the track history of the drone as a LineString, or a KML gx:Track, in GeoJSON or KML from the Accept header
+//	@Tags			drones
+//	@Accept			json
+//	@Produce		application/geo+json,application/vnd.google-earth.kml+xml
+//	@Param			id		path	string	true	"drone id"
+//	@Param			from	query	int		false	"start of the range in millisecond, default one hour before to"
+//	@Param			to		query	int		false	"end of the range in millisecond, default now"
+//	@Param			format	query	string	false	"geojson or kml, overrides the Accept header"
+//	@Success		200
+//	@Failure		400	{object}	types.ErrorResponse
+//	@Router			/drones/{id}/track/export [get]
+func exportTrackByIDHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := c.Param("id")
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		var from, to uint64
+		err := echo.QueryParamsBinder(c).
+			Uint64("from", &from).
+			Uint64("to", &to).
+			BindError()
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to bind query params")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		format, err := common.GetExportFormat(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		config.PrintDebugLog(ctx, "Export track of drone: %s - from %d to %d - format: %s", id, from, to, format)
+
+		doc, err := s.MainService.ExportTrack(ctx, id, from, to)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to export track of drone: %s", id)
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		return common.WriteMapExport(c, doc, format)
+	}
+}
@@ -0,0 +1,111 @@
+package restriction
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	hapi "172.21.5.249/air-trans/at-drone/internal/hapi"
+	service "172.21.5.249/air-trans/at-drone/internal/service"
+	types "172.21.5.249/air-trans/at-drone/internal/types"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func CreateRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.POST("/flight-restrictions", createHandler(s))
+}
+
+// Create flight restriction godoc
+//
+//	@Summary		Create a flight restriction
+//	@Description	Create a new flight restriction
+//	@Tags			flight-restrictions
+//	@Accept			json
+//	@Produce		json
+//	@Param			restriction	body		pb.FlightRestriction	true	"flight restriction body"
+//	@Param			eventAPI	query		bool					true	"event api call flag"
+//	@Success		200			{object}	pb.FlightRestriction
+//	@Failure		400			{object}	types.ErrorResponse
+//	@Router			/flight-restrictions [post]
+func createHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		u := &pb.FlightRestriction{}
+		if err := c.Bind(u); err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to bind data")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		eventAPI, _ := strconv.ParseBool(c.QueryParam("eventAPI"))
+		if !eventAPI {
+			u.ID = requestID
+		}
+
+		config.PrintDebugLog(ctx, "Create flight restriction: %+v", u)
+
+		_, err := s.MainService.CreateFlightRestriction(ctx, u, eventAPI)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to create flight restriction: %+v", u)
+
+			code := http.StatusInternalServerError
+			if errors.Is(err, service.ErrInvalidFlightRestriction) {
+				code = http.StatusBadRequest
+			}
+
+			return c.JSON(code, types.ErrorResponse{
+				Code:    code,
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusCreated, u)
+	}
+}
+
+func FindAllRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/flight-restrictions", findAllHandler(s))
+}
+
+// Find all flight restriction godoc
+//
+//	@Summary		Find all flight restriction
+//	@Description	Find all flight restriction
+//	@Tags			flight-restrictions
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	pb.FlightRestriction
+//	@Failure		400	{object}	types.ErrorResponse
+//	@Router			/flight-restrictions [get]
+func findAllHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		config.PrintDebugLog(ctx, "Find flight restriction all")
+
+		u, err := s.MainService.FindFlightRestrictionAll(ctx)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to find flight restriction all")
+
+			return c.JSON(http.StatusNotFound, types.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, u)
+	}
+}
@@ -0,0 +1,382 @@
+package geofence
+
+import (
+	"net/http"
+	"strconv"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	hapi "172.21.5.249/air-trans/at-drone/internal/hapi"
+	common "172.21.5.249/air-trans/at-drone/internal/hapi/handlers/common"
+	types "172.21.5.249/air-trans/at-drone/internal/types"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	queryoptions "go.jtlabs.io/query"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func CreateRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.POST("/geofences", createHandler(s))
+}
+
+// Create geofence godoc
+//
+//	@Summary		Create a geofence
+//	@Description	Create a new geofence
+//	@Tags			geofences
+//	@Accept			json
+//	@Produce		json
+//	@Param			geofence	body		pb.Geofence	true	"geofence body"
+//	@Param			eventAPI	query		bool		true	"event api call flag"
+//	@Success		200			{object}	pb.Geofence
+//	@Failure		400			{object}	types.ErrorResponse
+//	@Router			/geofences [post]
+func createHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		u := &pb.Geofence{}
+		if err := c.Bind(u); err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to bind data")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		eventAPI, _ := strconv.ParseBool(c.QueryParam("eventAPI"))
+		if !eventAPI {
+			u.ID = requestID
+		}
+
+		config.PrintDebugLog(ctx, "Create geofence: %+v", u)
+
+		_, err := s.MainService.CreateGeofence(ctx, u, eventAPI)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to create geofence: %+v", u)
+
+			return c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusCreated, u)
+	}
+}
+
+func DeleteByIDRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.DELETE("/geofences/:id", deleteByIDHandler(s))
+}
+
+// Delete geofence by ID godoc
+//
+//	@Summary		Delete geofence by ID
+//	@Description	Delete geofence by ID
+//	@Tags			geofences
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		string	true	"geofence id"
+//	@Param			eventAPI	query		bool	true	"event api call flag"
+//	@Success		200			{object}	pb.Geofence
+//	@Failure		400			{object}	types.ErrorResponse
+//	@Router			/geofences/{id} [delete]
+func deleteByIDHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		id := c.Param("id")
+		eventAPI, _ := strconv.ParseBool(c.QueryParam("eventAPI"))
+
+		config.PrintDebugLog(ctx, "Delete geofence by id: %s", id)
+
+		err := s.MainService.DeleteGeofenceByID(ctx, id, eventAPI)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to delete geofence by id: %s", id)
+
+			return c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, types.SucceedResponse{
+			Success: true,
+		})
+	}
+}
+
+func PatchByIDRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.PATCH("/geofences/:id", patchByIDHandler(s))
+}
+
+// Patch geofence by ID godoc
+//
+//	@Summary		Patch geofence by ID
+//	@Description	Patch geofence by ID use standard of JSON PATCH https://jsonpatch.com/
+//	@Tags			geofences
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		string			true	"geofence id"
+//	@Param			geofence	body		jsonpatch.Patch	true	"Patch operation format Array of Operation Add, Remove, Replace, Copy, Move, Test. Get example at https://jsonpatch.com/"
+//	@Param			eventAPI	query		bool			true	"event api call flag"
+//	@Success		200			{object}	pb.PatchResponse
+//	@Failure		400			{object}	types.ErrorResponse
+//	@Router			/geofences/{id} [patch]
+func patchByIDHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		patch := &jsonpatch.Patch{}
+		if err := c.Bind(patch); err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to bind data")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		id := c.Param("id")
+		eventAPI, _ := strconv.ParseBool(c.QueryParam("eventAPI"))
+
+		config.PrintDebugLog(ctx, "Patch geofence by id: %s: %+v", id, patch)
+
+		result, err := s.MainService.PatchGeofenceByID(ctx, patch, id, eventAPI)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to patch geofence by id: %s: %+v", id, patch)
+
+			return c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+func SearchRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/geofences/search", searchHandler(s))
+}
+
+// Search geofence godoc
+//
+//	@Summary		Search geofence
+//	@Description	Search geofence use Query option https://github.com/jtlabsio/mongo/
+//	@Tags			geofences
+//	@Accept			json
+//	@Produce		json
+//	@Param			page[page]	query		int	true	"page number"
+//	@Param			page[size]	query		int	true	"page size"
+//	@Success		200			{object}	pb.Geofence
+//	@Failure		400			{object}	types.ErrorResponse
+//	@Router			/geofences/search [get]
+func searchHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		opt, err := queryoptions.FromQuerystring(c.Request().URL.RequestURI())
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to get query option from string: %s", c.Request().URL.RequestURI())
+		}
+
+		config.PrintDebugLog(ctx, "Search geofence: %+v", opt)
+
+		result, count := s.MainService.SearchGeofence(ctx, opt)
+
+		config.PrintDebugLog(ctx, "Search geofence result: %d", count)
+
+		c.Response().Header().Set("x-total-count", strconv.FormatInt(count, 10))
+		return c.JSON(http.StatusOK, *result)
+	}
+}
+
+func FindByIDRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/geofences/:id", findByIDHandler(s))
+}
+
+// Find geofence by ID godoc
+//
+//	@Summary		Find geofence by ID
+//	@Description	Find geofence by ID
+//	@Tags			geofences
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string	true	"geofence id"
+//	@Success		200	{object}	pb.Geofence
+//	@Failure		400	{object}	types.ErrorResponse
+//	@Router			/geofences/{id} [get]
+func findByIDHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := c.Param("id")
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		config.PrintDebugLog(ctx, "Find geofence by id: %s", id)
+
+		u, err := s.MainService.FindGeofenceByID(ctx, id)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to find geofence by id: %s", id)
+
+			return c.JSON(http.StatusNotFound, types.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusOK, u)
+	}
+}
+
+func FindAllRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/geofences", findAllHandler(s))
+}
+
+// Find all geofence godoc
+//
+//	@Summary		Find all geofence
+//	@Description	Find all geofence
+//	@Tags			geofences
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	pb.Geofence
+//	@Failure		400	{object}	types.ErrorResponse
+//	@Router			/geofences [get]
+func findAllHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		config.PrintDebugLog(ctx, "Find geofence all")
+
+		u, err := s.MainService.FindGeofenceAll(ctx)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to find geofence all")
+
+			return c.JSON(http.StatusNotFound, types.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, u)
+	}
+}
+
+func UpdateByIDRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.PUT("/geofences/:id", updateByIDHandler(s))
+}
+
+// Update geofence by ID godoc
+//
+//	@Summary		Update geofence by ID
+//	@Description	Update geofence by ID
+//	@Tags			geofences
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		string		true	"geofence id"
+//	@Param			geofence	body		pb.Geofence	true	"geofence body"
+//	@Param			eventAPI	query		bool		true	"event api call flag"
+//	@Success		200			{object}	pb.Geofence
+//	@Failure		400			{object}	types.ErrorResponse
+//	@Router			/geofences/{id} [put]
+func updateByIDHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		u := &pb.Geofence{}
+		if err := c.Bind(u); err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to bind data")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		id := c.Param("id")
+		eventAPI, _ := strconv.ParseBool(c.QueryParam("eventAPI"))
+
+		config.PrintDebugLog(ctx, "Update geofence by id: %s: %+v", id, u)
+
+		result, err := s.MainService.UpdateGeofenceByID(ctx, u, id, eventAPI)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to update geofence by id: %s: %+v", id, u)
+
+			return c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+func ExportRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/geofences/export", exportHandler(s))
+}
+
+func ExportByIDRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/geofences/:id/export", exportHandler(s))
+}
+
+// Export geofences godoc
+//
+//	@Summary		Export geofences
+//	@Description	Export the active geofences, or the one with the id, as polygons with altitude_floor and altitude_ceiling, in GeoJSON or KML from the Accept header
+//	@Tags			geofences
+//	@Accept			json
+//	@Produce		application/geo+json,application/vnd.google-earth.kml+xml
+//	@Param			id		path	string	false	"geofence id"
+//	@Param			format	query	string	false	"geojson or kml, overrides the Accept header"
+//	@Success		200
+//	@Failure		400	{object}	types.ErrorResponse
+//	@Failure		404	{object}	types.ErrorResponse
+//	@Router			/geofences/export [get]
+//	@Router			/geofences/{id}/export [get]
+func exportHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := c.Param("id")
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		format, err := common.GetExportFormat(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		config.PrintDebugLog(ctx, "Export geofences: %s - format: %s", id, format)
+
+		doc, err := s.MainService.ExportGeofences(ctx, id)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to export geofences: %s", id)
+
+			return c.JSON(http.StatusNotFound, types.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: err.Error(),
+			})
+		}
+
+		return common.WriteMapExport(c, doc, format)
+	}
+}
@@ -3,8 +3,14 @@ package handlers
 import (
 	hapi "172.21.5.249/air-trans/at-drone/internal/hapi"
 	common "172.21.5.249/air-trans/at-drone/internal/hapi/handlers/common"
+	contingencyCommand "172.21.5.249/air-trans/at-drone/internal/hapi/handlers/contingency_command"
+	corridor "172.21.5.249/air-trans/at-drone/internal/hapi/handlers/corridor"
 	drone "172.21.5.249/air-trans/at-drone/internal/hapi/handlers/drone"
+	flightRestriction "172.21.5.249/air-trans/at-drone/internal/hapi/handlers/flight_restriction"
+	geofence "172.21.5.249/air-trans/at-drone/internal/hapi/handlers/geofence"
+	infringement "172.21.5.249/air-trans/at-drone/internal/hapi/handlers/infringement"
 	objectTrack "172.21.5.249/air-trans/at-drone/internal/hapi/handlers/object_track"
+	order "172.21.5.249/air-trans/at-drone/internal/hapi/handlers/order"
 	trackHistory "172.21.5.249/air-trans/at-drone/internal/hapi/handlers/track_history"
 	"172.21.5.249/air-trans/at-drone/internal/hapi/handlers/websocket"
 
@@ -26,7 +32,47 @@ func AttackAllRoutes(s *hapi.Server) {
 		drone.FindAllRoute(s),
 		drone.SearchRoute(s),
 		drone.FindByIDRoute(s),
+		drone.FindProgressByIDRoute(s),
 		drone.UpdateByIDRoute(s),
+		drone.ExportTrackByIDRoute(s),
+
+		corridor.CreateRoute(s),
+		corridor.DeleteByIDRoute(s),
+		corridor.PatchByIDRoute(s),
+		corridor.FindAllRoute(s),
+		corridor.SearchRoute(s),
+		corridor.FindByIDRoute(s),
+		corridor.UpdateByIDRoute(s),
+		corridor.ValidateRoute(s),
+		corridor.ImportRoute(s),
+		corridor.ExportByIDRoute(s),
+		corridor.AmendByIDRoute(s),
+		corridor.FindVersionsByIDRoute(s),
+
+		geofence.CreateRoute(s),
+		geofence.DeleteByIDRoute(s),
+		geofence.PatchByIDRoute(s),
+		geofence.FindAllRoute(s),
+		geofence.SearchRoute(s),
+		geofence.FindByIDRoute(s),
+		geofence.UpdateByIDRoute(s),
+		geofence.ExportRoute(s),
+		geofence.ExportByIDRoute(s),
+
+		flightRestriction.CreateRoute(s),
+		flightRestriction.FindAllRoute(s),
+
+		infringement.SearchRoute(s),
+		infringement.StatsRoute(s),
+		infringement.FindByIDRoute(s),
+		infringement.AcknowledgeRoute(s),
+		infringement.CommentRoute(s),
+		infringement.ResolveRoute(s),
+
+		contingencyCommand.SearchRoute(s),
+
+		order.ConformanceRoute(s),
+		order.RouteImportRoute(s),
 
 		trackHistory.CreateRoute(s),
 		trackHistory.DeleteByIDRoute(s),
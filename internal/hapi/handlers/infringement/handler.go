@@ -0,0 +1,336 @@
+package infringement
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	hapi "172.21.5.249/air-trans/at-drone/internal/hapi"
+	common "172.21.5.249/air-trans/at-drone/internal/hapi/handlers/common"
+	service "172.21.5.249/air-trans/at-drone/internal/service"
+	types "172.21.5.249/air-trans/at-drone/internal/types"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	queryoptions "go.jtlabs.io/query"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func SearchRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/infringements/search", searchHandler(s))
+}
+
+// Search infringement godoc
+//
+//	@Summary		Search infringement
+//	@Description	Search infringement use Query option https://github.com/jtlabsio/mongo/
+//	@Tags			infringements
+//	@Accept			json
+//	@Produce		json
+//	@Param			page[page]	query		int	true	"page number"
+//	@Param			page[size]	query		int	true	"page size"
+//	@Success		200			{object}	pb.Infringement
+//	@Failure		400			{object}	types.ErrorResponse
+//	@Router			/infringements/search [get]
+func searchHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		opt, err := queryoptions.FromQuerystring(c.Request().URL.RequestURI())
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to get query option from string: %s", c.Request().URL.RequestURI())
+		}
+
+		config.PrintDebugLog(ctx, "Search infringement: %+v", opt)
+
+		result, count := s.MainService.SearchInfringement(ctx, opt)
+
+		config.PrintDebugLog(ctx, "Search infringement result: %d", count)
+
+		c.Response().Header().Set("x-total-count", strconv.FormatInt(count, 10))
+		return c.JSON(http.StatusOK, *result)
+	}
+}
+
+func StatsRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/infringements/stats", statsHandler(s))
+}
+
+// Infringement statistics godoc
+//
+//	@Summary		Infringement statistics
+//	@Description	Count infringements started in a time range per drone, route and day
+//	@Tags			infringements
+//	@Accept			json
+//	@Produce		json
+//	@Param			from	query		int	false	"start of the range in millisecond"
+//	@Param			to		query		int	false	"end of the range in millisecond, default now"
+//	@Success		200		{object}	service.InfringementStats
+//	@Failure		400		{object}	types.ErrorResponse
+//	@Router			/infringements/stats [get]
+func statsHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		var from, to uint64
+		err := echo.QueryParamsBinder(c).
+			Uint64("from", &from).
+			Uint64("to", &to).
+			BindError()
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to bind query params")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		config.PrintDebugLog(ctx, "Infringement stats from %d to %d", from, to)
+
+		result, err := s.MainService.InfringementStats(ctx, from, to)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to get infringement stats")
+
+			return c.JSON(http.StatusInternalServerError, types.ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+func FindByIDRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/infringements/:id", findByIDHandler(s))
+}
+
+// Find infringement by ID godoc
+//
+//	@Summary		Find infringement by ID
+//	@Description	Find infringement by ID
+//	@Tags			infringements
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string	true	"infringement id"
+//	@Success		200	{object}	pb.Infringement
+//	@Failure		400	{object}	types.ErrorResponse
+//	@Router			/infringements/{id} [get]
+func findByIDHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := c.Param("id")
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		config.PrintDebugLog(ctx, "Find infringement by id: %s", id)
+
+		u, err := s.MainService.FindInfringementByID(ctx, id)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to find infringement by id: %s", id)
+
+			return c.JSON(http.StatusNotFound, types.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: err.Error(),
+			})
+		}
+		return c.JSON(http.StatusOK, u)
+	}
+}
+
+func AcknowledgeRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.POST("/infringements/:id/acknowledge", acknowledgeHandler(s))
+}
+
+// Acknowledge infringement godoc
+//
+//	@Summary		Acknowledge infringement
+//	@Description	Take ownership of an open infringement, the user comes from the JWT
+//	@Tags			infringements
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string							true	"infringement id"
+//	@Param			action	body		pb.InfringementTimelineEntry	true	"optional comment"
+//	@Success		200		{object}	pb.Infringement
+//	@Failure		400		{object}	types.ErrorResponse
+//	@Failure		401		{object}	types.ErrorResponse
+//	@Failure		409		{object}	types.ErrorResponse
+//	@Router			/infringements/{id}/acknowledge [post]
+func acknowledgeHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		u := &pb.InfringementTimelineEntry{}
+		if err := c.Bind(u); err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to bind data")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		id := c.Param("id")
+		user, err := common.GetUser(c, s.Config.JWTTokenConfig.ValidateJwt)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to get user")
+
+			return c.JSON(http.StatusUnauthorized, types.ErrorResponse{
+				Code:    http.StatusUnauthorized,
+				Message: err.Error(),
+			})
+		}
+
+		config.PrintDebugLog(ctx, "Acknowledge infringement by id: %s - user: %s", id, user)
+
+		result, err := s.MainService.AcknowledgeInfringement(ctx, id, user, u.Comment)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to acknowledge infringement by id: %s", id)
+
+			return actionError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+func CommentRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.POST("/infringements/:id/comments", commentHandler(s))
+}
+
+// Comment infringement godoc
+//
+//	@Summary		Comment infringement
+//	@Description	Add a comment to the infringement timeline, the user comes from the JWT
+//	@Tags			infringements
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string							true	"infringement id"
+//	@Param			action	body		pb.InfringementTimelineEntry	true	"comment"
+//	@Success		200		{object}	pb.Infringement
+//	@Failure		400		{object}	types.ErrorResponse
+//	@Failure		401		{object}	types.ErrorResponse
+//	@Failure		409		{object}	types.ErrorResponse
+//	@Router			/infringements/{id}/comments [post]
+func commentHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		u := &pb.InfringementTimelineEntry{}
+		if err := c.Bind(u); err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to bind data")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		id := c.Param("id")
+		user, err := common.GetUser(c, s.Config.JWTTokenConfig.ValidateJwt)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to get user")
+
+			return c.JSON(http.StatusUnauthorized, types.ErrorResponse{
+				Code:    http.StatusUnauthorized,
+				Message: err.Error(),
+			})
+		}
+
+		config.PrintDebugLog(ctx, "Comment infringement by id: %s - user: %s", id, user)
+
+		result, err := s.MainService.CommentInfringement(ctx, id, user, u.Comment)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to comment infringement by id: %s", id)
+
+			return actionError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+func ResolveRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.POST("/infringements/:id/resolve", resolveHandler(s))
+}
+
+// Resolve infringement godoc
+//
+//	@Summary		Resolve infringement
+//	@Description	Resolve the infringement with a cause code, the user comes from the JWT
+//	@Tags			infringements
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string							true	"infringement id"
+//	@Param			action	body		pb.InfringementTimelineEntry	true	"cause code and optional comment"
+//	@Success		200		{object}	pb.Infringement
+//	@Failure		400		{object}	types.ErrorResponse
+//	@Failure		401		{object}	types.ErrorResponse
+//	@Failure		409		{object}	types.ErrorResponse
+//	@Router			/infringements/{id}/resolve [post]
+func resolveHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		u := &pb.InfringementTimelineEntry{}
+		if err := c.Bind(u); err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to bind data")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		id := c.Param("id")
+		user, err := common.GetUser(c, s.Config.JWTTokenConfig.ValidateJwt)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to get user")
+
+			return c.JSON(http.StatusUnauthorized, types.ErrorResponse{
+				Code:    http.StatusUnauthorized,
+				Message: err.Error(),
+			})
+		}
+
+		config.PrintDebugLog(ctx, "Resolve infringement by id: %s - user: %s", id, user)
+
+		result, err := s.MainService.ResolveInfringement(ctx, id, user, u.CauseCode, u.Comment)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to resolve infringement by id: %s", id)
+
+			return actionError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+func actionError(c echo.Context, err error) error {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrInfringementStatus):
+		code = http.StatusConflict
+	case errors.Is(err, service.ErrNoCauseCode), errors.Is(err, service.ErrEmptyComment):
+		code = http.StatusBadRequest
+	}
+
+	return c.JSON(code, types.ErrorResponse{
+		Code:    code,
+		Message: err.Error(),
+	})
+}
@@ -0,0 +1,137 @@
+package order
+
+import (
+	"fmt"
+	"net/http"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	hapi "172.21.5.249/air-trans/at-drone/internal/hapi"
+	common "172.21.5.249/air-trans/at-drone/internal/hapi/handlers/common"
+	types "172.21.5.249/air-trans/at-drone/internal/types"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+func ConformanceRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.GET("/orders/:id/conformance", conformanceHandler(s))
+}
+
+// Order conformance godoc
+//
+//	@Summary		Order conformance
+//	@Description	Replay the track history of the order against its corridor, as JSON or as a CSV time series with format=csv
+//	@Tags			orders
+//	@Accept			json
+//	@Produce		json,text/csv
+//	@Param			id			path		string	true	"order id"
+//	@Param			drone_id	query		string	false	"drone id, default the drone of the order"
+//	@Param			from		query		int		false	"start of the range in millisecond, default the creation of the order"
+//	@Param			to			query		int		false	"end of the range in millisecond, default the delivery of the order or now"
+//	@Param			format		query		string	false	"json or csv"
+//	@Success		200			{object}	service.ConformanceReport
+//	@Failure		400			{object}	types.ErrorResponse
+//	@Failure		404			{object}	types.ErrorResponse
+//	@Router			/orders/{id}/conformance [get]
+func conformanceHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		id := c.Param("id")
+
+		var droneID, format string
+		var from, to uint64
+		err := echo.QueryParamsBinder(c).
+			String("drone_id", &droneID).
+			Uint64("from", &from).
+			Uint64("to", &to).
+			String("format", &format).
+			BindError()
+		if err == nil && format != "" && format != "json" && format != "csv" {
+			err = fmt.Errorf("unsupported format: %s", format)
+		}
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to bind query params")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		config.PrintDebugLog(ctx, "Conformance of order: %s - drone: %s - from %d to %d", id, droneID, from, to)
+
+		report, err := s.MainService.OrderConformance(ctx, id, droneID, from, to)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to get conformance of order: %s", id)
+
+			return c.JSON(http.StatusNotFound, types.ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: err.Error(),
+			})
+		}
+
+		if format != "csv" {
+			return c.JSON(http.StatusOK, report)
+		}
+
+		c.Response().Header().Set(echo.HeaderContentType, "text/csv")
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=conformance_%s.csv", id))
+		c.Response().WriteHeader(http.StatusOK)
+
+		return report.WriteCSV(c.Response())
+	}
+}
+
+func RouteImportRoute(s *hapi.Server) *echo.Route {
+	return s.Router.Root.POST("/orders/:id/route/import", routeImportHandler(s))
+}
+
+// Import order flight route godoc
+//
+//	@Summary		Import order flight route
+//	@Description	Replace the flight route of the order with a QGroundControl .plan, QGC WPL 110, KML or GeoJSON route, uploaded as the multipart field file or as the body
+//	@Tags			orders
+//	@Accept			mpfd,json,plain,xml
+//	@Produce		json
+//	@Param			id					path		string	true	"order id"
+//	@Param			file				formData	file	false	"route file"
+//	@Param			format				query		string	false	"plan, wpl, kml or geojson, detected when empty"
+//	@Param			altitude_reference	query		string	false	"AMSL, RELATIVE or TERRAIN for the altitudes the format leaves unqualified, default AMSL"
+//	@Param			home_altitude		query		number	false	"home altitude in meter AMSL, default the home position of the file"
+//	@Success		200					{object}	pb.Order
+//	@Failure		400					{object}	types.ValidationErrorResponse
+//	@Router			/orders/{id}/route/import [post]
+func routeImportHandler(s *hapi.Server) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := uuid.NewString()
+		ctx := log.With().Str("x-request-id", requestID).Logger().WithContext(c.Request().Context())
+		c.Response().Header().Set("x-request-id", requestID)
+
+		id := c.Param("id")
+
+		data, opt, err := common.GetRouteUpload(c)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to read route upload")
+
+			return c.JSON(http.StatusBadRequest, types.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+		}
+
+		config.PrintDebugLog(ctx, "Import flight route of order %s from %s", id, opt.FileName)
+
+		result, err := s.MainService.ImportOrderRoute(ctx, id, data, opt)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to import flight route of order: %s", id)
+
+			return common.RouteImportError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, result)
+	}
+}
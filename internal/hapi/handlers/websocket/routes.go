@@ -17,18 +17,42 @@ var upgrader = websocket.Upgrader{
 
 func RegisterRoutes(s *hapi.Server) []*echo.Route {
 	return []*echo.Route{
-		s.Router.Root.GET("/ws/flight-containment", handler(s, service.EventFlightContainmentInfringement)),
+		s.Router.Root.GET("/ws/flight-containment", handler(s,
+			service.EventFlightContainmentInfringement,
+			service.EventFlightContainmentWarning,
+			service.EventFlightContainmentRecovered,
+			service.EventFlightContainmentPredicted,
+		)),
+		s.Router.Root.GET("/ws/geofence", handler(s,
+			service.EventGeofenceViolated,
+			service.EventFlightRestrictionActivated,
+			service.EventFlightRestrictionDeactivated,
+		)),
+		s.Router.Root.GET("/ws/separation", handler(s,
+			service.EventSeparationConflict,
+		)),
+		s.Router.Root.GET("/ws/intrusion", handler(s,
+			service.EventIntrusionDetected,
+		)),
+		s.Router.Root.GET("/ws/corridor", handler(s,
+			service.EventCorridorAmended,
+		)),
+		s.Router.Root.GET("/ws/infringement", handler(s,
+			service.EventInfringementAcknowledged,
+			service.EventInfringementResolved,
+			service.EventInfringementUnacknowledged,
+		)),
 	}
 }
 
-func handler(s *hapi.Server, event service.NotificationEvent) echo.HandlerFunc {
+func handler(s *hapi.Server, events ...service.NotificationEvent) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
 		if err != nil {
 			return err
 		}
 		defer conn.Close()
-		messages, unsubscribe := s.MainService.Notifier().Subscribe(event)
+		messages, unsubscribe := s.MainService.Notifier().Subscribe(events...)
 		defer unsubscribe()
 		done := make(chan struct{})
 		go func() {
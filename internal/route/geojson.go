@@ -0,0 +1,116 @@
+package route
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+type geoJSONFeature struct {
+	Type       string           `json:"type"`
+	Geometry   *geoJSONGeometry `json:"geometry"`
+	Properties struct {
+		AltitudeReference string `json:"altitude_reference"`
+	} `json:"properties"`
+}
+
+type geoJSONObject struct {
+	geoJSONFeature
+	Coordinates json.RawMessage  `json:"coordinates"`
+	Features    []geoJSONFeature `json:"features"`
+}
+
+// parseGeoJSON reads a LineString given as a geometry, a feature or the only
+// LineString of a feature collection. A feature may set its altitude_reference
+// property, Item is the index of the position.
+func parseGeoJSON(data []byte, reference AltitudeReference) (*Plan, issues) {
+	var is issues
+
+	object := geoJSONObject{}
+	err := json.Unmarshal(data, &object)
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			is.add(lineAt(data, syntaxErr.Offset), 0, "%v", err)
+		case errors.As(err, &typeErr):
+			is.add(lineAt(data, typeErr.Offset), 0, "%v", err)
+		default:
+			is.add(0, 0, "%v", err)
+		}
+
+		return nil, is
+	}
+
+	features := []geoJSONFeature{}
+	switch object.Type {
+	case "FeatureCollection":
+		for _, f := range object.Features {
+			if f.Geometry != nil && f.Geometry.Type == "LineString" {
+				features = append(features, f)
+			}
+		}
+	case "Feature":
+		if object.Geometry != nil && object.Geometry.Type == "LineString" {
+			features = append(features, object.geoJSONFeature)
+		}
+	case "LineString":
+		features = append(features, geoJSONFeature{Geometry: &geoJSONGeometry{Type: object.Type, Coordinates: object.Coordinates}})
+	default:
+		is.add(0, 0, "unsupported GeoJSON type: %s", object.Type)
+
+		return nil, is
+	}
+
+	if len(features) != 1 {
+		is.add(0, 0, "found %d LineStrings, expected one", len(features))
+
+		return nil, is
+	}
+
+	feature := features[0]
+	if feature.Properties.AltitudeReference != "" {
+		reference, err = ParseAltitudeReference(feature.Properties.AltitudeReference)
+		if err != nil {
+			is.add(0, 0, "%v", err)
+
+			return nil, is
+		}
+	}
+
+	coordinates := [][]float64{}
+	err = json.Unmarshal(feature.Geometry.Coordinates, &coordinates)
+	if err != nil {
+		is.add(0, 0, "LineString coordinates: %v", err)
+
+		return nil, is
+	}
+
+	plan := &Plan{}
+	for i, position := range coordinates {
+		if len(position) < 2 || len(position) > 3 {
+			is.add(0, i+1, "position has %d values, expected [lon, lat] or [lon, lat, alt]", len(position))
+
+			continue
+		}
+
+		w := Waypoint{
+			Latitude:  position[1],
+			Longitude: position[0],
+			Reference: reference,
+			Item:      i + 1,
+		}
+		if len(position) == 3 {
+			w.Altitude = position[2]
+		}
+
+		plan.Waypoints = append(plan.Waypoints, w)
+	}
+
+	return plan, is
+}
@@ -0,0 +1,139 @@
+package route
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// kmlAltitudeModes maps the KML altitudeMode, clampToGround being the KML default
+// is left to the reference of the upload.
+var kmlAltitudeModes = map[string]AltitudeReference{
+	"absolute":         AltitudeAMSL,
+	"relativeToGround": AltitudeTerrain,
+}
+
+type kmlLineString struct {
+	mode        string
+	coordinates string
+	line        int // line of the coordinates element
+}
+
+// parseKML reads the first LineString of the document, Item is the index of the
+// coordinate tuple.
+func parseKML(data []byte, reference AltitudeReference) (*Plan, issues) {
+	var is issues
+
+	lineStrings := []kmlLineString{}
+	var current *kmlLineString
+	var text strings.Builder
+	textLine := 0
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line, _ := decoder.InputPos()
+			is.add(line, 0, "%v", err)
+
+			return nil, is
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "LineString":
+				current = &kmlLineString{}
+			case "coordinates", "altitudeMode":
+				text.Reset()
+				textLine, _ = decoder.InputPos()
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if current == nil {
+				continue
+			}
+
+			switch t.Name.Local {
+			case "altitudeMode":
+				current.mode = strings.TrimSpace(text.String())
+			case "coordinates":
+				current.coordinates = text.String()
+				current.line = textLine
+			case "LineString":
+				lineStrings = append(lineStrings, *current)
+				current = nil
+			}
+		}
+	}
+
+	switch {
+	case len(lineStrings) == 0:
+		is.add(0, 0, "no LineString in the document")
+
+		return nil, is
+	case len(lineStrings) > 1:
+		is.add(lineStrings[1].line, 0, "found %d LineStrings, expected one", len(lineStrings))
+
+		return nil, is
+	}
+
+	ls := lineStrings[0]
+	if ls.mode != "" && ls.mode != "clampToGround" {
+		r, ok := kmlAltitudeModes[ls.mode]
+		if !ok {
+			is.add(ls.line, 0, "altitudeMode %s is not supported", ls.mode)
+
+			return nil, is
+		}
+		reference = r
+	}
+
+	plan := &Plan{}
+	line := ls.line
+	item := 0
+	for _, row := range strings.Split(ls.coordinates, "\n") {
+		for _, tuple := range strings.Fields(row) {
+			item++
+
+			parts := strings.Split(tuple, ",")
+			if len(parts) < 2 || len(parts) > 3 {
+				is.add(line, item, "expected lon,lat[,alt], got %s", tuple)
+
+				continue
+			}
+
+			values := [3]float64{}
+			bad := false
+			for i, p := range parts {
+				v, err := strconv.ParseFloat(p, 64)
+				if err != nil {
+					is.add(line, item, "%v", err)
+					bad = true
+				}
+				values[i] = v
+			}
+			if bad {
+				continue
+			}
+
+			plan.Waypoints = append(plan.Waypoints, Waypoint{
+				Latitude:  values[1],
+				Longitude: values[0],
+				Altitude:  values[2],
+				Reference: reference,
+				Line:      line,
+				Item:      item,
+			})
+		}
+		line++
+	}
+
+	return plan, is
+}
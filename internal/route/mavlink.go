@@ -0,0 +1,221 @@
+package route
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// MAV_CMD of the mission items that carry a position
+var navCommands = map[int]string{
+	16: "NAV_WAYPOINT",
+	17: "NAV_LOITER_UNLIM",
+	18: "NAV_LOITER_TURNS",
+	19: "NAV_LOITER_TIME",
+	21: "NAV_LAND",
+	22: "NAV_TAKEOFF",
+	31: "NAV_LOITER_TO_ALT",
+	82: "NAV_SPLINE_WAYPOINT",
+	84: "NAV_VTOL_TAKEOFF",
+	85: "NAV_VTOL_LAND",
+}
+
+// MAV_FRAME of the global frames
+var navFrames = map[int]AltitudeReference{
+	0:  AltitudeAMSL,     // GLOBAL
+	3:  AltitudeRelative, // GLOBAL_RELATIVE_ALT
+	5:  AltitudeAMSL,     // GLOBAL_INT
+	6:  AltitudeRelative, // GLOBAL_RELATIVE_ALT_INT
+	10: AltitudeTerrain,  // GLOBAL_TERRAIN_ALT
+	11: AltitudeTerrain,  // GLOBAL_TERRAIN_ALT_INT
+}
+
+// missionItem turns a MAVLink mission item into a waypoint, ok is false for the
+// commands without a position.
+func missionItem(is *issues, line, item, frame, command int, lat, lon, alt float64) (Waypoint, bool) {
+	if _, ok := navCommands[command]; !ok {
+		return Waypoint{}, false
+	}
+
+	reference, ok := navFrames[frame]
+	if !ok {
+		is.add(line, item, "frame %d of command %d is not a global frame", frame, command)
+
+		return Waypoint{}, false
+	}
+
+	// Takeoff and land without a position happen where the drone is
+	if lat == 0 && lon == 0 && (command == 21 || command == 22 || command == 84 || command == 85) {
+		return Waypoint{}, false
+	}
+
+	return Waypoint{
+		Latitude:  lat,
+		Longitude: lon,
+		Altitude:  alt,
+		Reference: reference,
+		Line:      line,
+		Item:      item,
+	}, true
+}
+
+type qgcPlan struct {
+	FileType string `json:"fileType"`
+	Mission  *struct {
+		Items []struct {
+			Type     string     `json:"type"`
+			Command  int        `json:"command"`
+			Frame    int        `json:"frame"`
+			Params   []*float64 `json:"params"`
+			Complex  string     `json:"complexItemType"`
+			DoJumpID int        `json:"doJumpId"`
+		} `json:"items"`
+		PlannedHomePosition []float64 `json:"plannedHomePosition"`
+	} `json:"mission"`
+}
+
+func parsePlan(data []byte) (*Plan, issues) {
+	var is issues
+
+	file := qgcPlan{}
+	err := json.Unmarshal(data, &file)
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			is.add(lineAt(data, syntaxErr.Offset), 0, "%v", err)
+		case errors.As(err, &typeErr):
+			is.add(lineAt(data, typeErr.Offset), 0, "%v", err)
+		default:
+			is.add(0, 0, "%v", err)
+		}
+
+		return nil, is
+	}
+
+	if file.FileType != "Plan" || file.Mission == nil {
+		is.add(0, 0, "not a QGroundControl plan")
+
+		return nil, is
+	}
+
+	plan := &Plan{}
+	if home := file.Mission.PlannedHomePosition; len(home) == 3 {
+		plan.Home = &Waypoint{Latitude: home[0], Longitude: home[1], Altitude: home[2], Reference: AltitudeAMSL}
+	}
+
+	for i, it := range file.Mission.Items {
+		item := i + 1
+		if it.DoJumpID > 0 {
+			item = it.DoJumpID
+		}
+
+		if it.Type != "SimpleItem" {
+			is.add(0, item, "%s %s is not supported, convert it to waypoints", it.Type, it.Complex)
+
+			continue
+		}
+		if len(it.Params) != 7 {
+			is.add(0, item, "has %d params, expected 7", len(it.Params))
+
+			continue
+		}
+
+		param := func(i int) float64 {
+			if it.Params[i] == nil {
+				return 0
+			}
+
+			return *it.Params[i]
+		}
+
+		w, ok := missionItem(&is, 0, item, it.Frame, it.Command, param(4), param(5), param(6))
+		if ok {
+			plan.Waypoints = append(plan.Waypoints, w)
+		}
+	}
+
+	return plan, is
+}
+
+// parseWPL reads the tab separated lines
+// INDEX CURRENT FRAME COMMAND P1 P2 P3 P4 LAT LON ALT AUTOCONTINUE
+// where index 0 is the home position.
+func parseWPL(data []byte) (*Plan, issues) {
+	var is issues
+	plan := &Plan{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		if line == 1 {
+			if !strings.HasPrefix(text, "QGC WPL 110") {
+				is.add(line, 0, "expected header QGC WPL 110")
+
+				return nil, is
+			}
+
+			continue
+		}
+		if text == "" {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 12 {
+			is.add(line, 0, "has %d fields, expected 12", len(fields))
+
+			continue
+		}
+
+		var err error
+		ints := [4]int{}
+		floats := [3]float64{}
+		bad := false
+		for i, f := range fields[:4] {
+			ints[i], err = strconv.Atoi(f)
+			if err != nil {
+				is.add(line, 0, "field %d: %v", i+1, err)
+				bad = true
+			}
+		}
+		for i, f := range fields[8:11] {
+			floats[i], err = strconv.ParseFloat(f, 64)
+			if err != nil {
+				is.add(line, 0, "field %d: %v", i+9, err)
+				bad = true
+			}
+		}
+		if bad {
+			continue
+		}
+
+		index, frame, command := ints[0], ints[2], ints[3]
+		if index == 0 {
+			plan.Home = &Waypoint{Latitude: floats[0], Longitude: floats[1], Altitude: floats[2], Reference: AltitudeAMSL, Line: line}
+
+			continue
+		}
+
+		w, ok := missionItem(&is, line, index, frame, command, floats[0], floats[1], floats[2])
+		if ok {
+			plan.Waypoints = append(plan.Waypoints, w)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		is.add(line, 0, "%v", err)
+	}
+	if line == 0 {
+		is.add(0, 0, "file is empty")
+	}
+
+	return plan, is
+}
@@ -0,0 +1,231 @@
+// Package route parses mission routes exported by planning tools into waypoints.
+//
+// Latitudes and longitudes are in degree and altitudes in meter, relative to the
+// reference recorded on each waypoint until Resolve turns them into AMSL.
+package route
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatPlan    Format = "plan"    // QGroundControl .plan JSON
+	FormatWPL     Format = "wpl"     // MAVLink QGC WPL 110 text
+	FormatKML     Format = "kml"     // Google Earth KML
+	FormatGeoJSON Format = "geojson" // GeoJSON LineString
+)
+
+type AltitudeReference string
+
+const (
+	AltitudeAMSL     AltitudeReference = "AMSL"
+	AltitudeRelative AltitudeReference = "RELATIVE" // above the home position
+	AltitudeTerrain  AltitudeReference = "TERRAIN"  // above the ground under the waypoint
+)
+
+func ParseAltitudeReference(s string) (AltitudeReference, error) {
+	switch r := AltitudeReference(strings.ToUpper(s)); r {
+	case AltitudeAMSL, AltitudeRelative, AltitudeTerrain:
+		return r, nil
+	case "":
+		return AltitudeAMSL, nil
+	}
+
+	return "", fmt.Errorf("unknown altitude reference: %s", s)
+}
+
+type Waypoint struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+	Reference AltitudeReference
+	Line      int // 0 when the format has no meaningful line
+	Item      int
+}
+
+type Plan struct {
+	Waypoints []Waypoint
+	Home      *Waypoint // AMSL, nil when the file has none
+}
+
+// Issue locates a problem in the uploaded file, Line and Item are 1-based and
+// omitted when unknown.
+type Issue struct {
+	Line    int    `json:"line,omitempty"`
+	Item    int    `json:"item,omitempty"`
+	Message string `json:"message"`
+}
+
+type ParseError struct {
+	Issues []Issue
+}
+
+func (e *ParseError) Error() string {
+	if len(e.Issues) == 0 {
+		return "invalid route"
+	}
+
+	first := e.Issues[0]
+	msg := first.Message
+	if first.Item > 0 {
+		msg = fmt.Sprintf("item %d: %s", first.Item, msg)
+	}
+	if first.Line > 0 {
+		msg = fmt.Sprintf("line %d: %s", first.Line, msg)
+	}
+	if len(e.Issues) > 1 {
+		msg = fmt.Sprintf("%s (and %d more)", msg, len(e.Issues)-1)
+	}
+
+	return msg
+}
+
+type issues []Issue
+
+func (is *issues) add(line, item int, format string, args ...interface{}) {
+	*is = append(*is, Issue{Line: line, Item: item, Message: fmt.Sprintf(format, args...)})
+}
+
+func (is issues) err() error {
+	if len(is) == 0 {
+		return nil
+	}
+
+	return &ParseError{Issues: is}
+}
+
+// lineAt returns the 1-based line of a byte offset.
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// DetectFormat guesses the format from the file extension, then from the content.
+func DetectFormat(name string, data []byte) (Format, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".plan":
+		return FormatPlan, nil
+	case ".waypoints", ".txt", ".wpl", ".mission":
+		return FormatWPL, nil
+	case ".kml":
+		return FormatKML, nil
+	case ".geojson":
+		return FormatGeoJSON, nil
+	}
+
+	head := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(head, []byte("QGC WPL")):
+		return FormatWPL, nil
+	case bytes.HasPrefix(head, []byte("<")):
+		return FormatKML, nil
+	case bytes.HasPrefix(head, []byte("{")):
+		if bytes.Contains(head, []byte(`"fileType"`)) {
+			return FormatPlan, nil
+		}
+
+		return FormatGeoJSON, nil
+	}
+
+	return "", &ParseError{Issues: []Issue{{Message: "unknown route format"}}}
+}
+
+// Parse reads the waypoints of a route. reference applies to the altitudes the
+// format leaves unqualified.
+func Parse(data []byte, format Format, reference AltitudeReference) (*Plan, error) {
+	var plan *Plan
+	var is issues
+
+	switch format {
+	case FormatPlan:
+		plan, is = parsePlan(data)
+	case FormatWPL:
+		plan, is = parseWPL(data)
+	case FormatKML:
+		plan, is = parseKML(data, reference)
+	case FormatGeoJSON:
+		plan, is = parseGeoJSON(data, reference)
+	default:
+		return nil, &ParseError{Issues: []Issue{{Message: fmt.Sprintf("unsupported route format: %s", format)}}}
+	}
+
+	if plan != nil {
+		for _, w := range plan.Waypoints {
+			validate(&is, w)
+		}
+		if len(is) == 0 && len(plan.Waypoints) < 2 {
+			is.add(0, 0, "route has %d waypoints, need at least 2", len(plan.Waypoints))
+		}
+	}
+
+	if err := is.err(); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func validate(is *issues, w Waypoint) {
+	for _, v := range []float64{w.Latitude, w.Longitude, w.Altitude} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			is.add(w.Line, w.Item, "coordinate is not a number")
+
+			return
+		}
+	}
+
+	if w.Latitude < -90 || w.Latitude > 90 {
+		is.add(w.Line, w.Item, "latitude %v out of range", w.Latitude)
+	}
+	if w.Longitude < -180 || w.Longitude > 180 {
+		is.add(w.Line, w.Item, "longitude %v out of range", w.Longitude)
+	}
+}
+
+// Resolve returns the waypoints with AMSL altitudes. Relative altitudes are taken
+// above homeAltitude and terrain altitudes above the ground elevation.
+func (p *Plan) Resolve(homeAltitude *float64, ground func(lat, lon float64) (float64, error)) ([]Waypoint, error) {
+	if homeAltitude == nil && p.Home != nil {
+		homeAltitude = &p.Home.Altitude
+	}
+
+	var is issues
+	result := make([]Waypoint, 0, len(p.Waypoints))
+	for _, w := range p.Waypoints {
+		switch w.Reference {
+		case AltitudeRelative:
+			if homeAltitude == nil {
+				is.add(w.Line, w.Item, "relative altitude needs a home altitude")
+
+				continue
+			}
+			w.Altitude += *homeAltitude
+		case AltitudeTerrain:
+			elevation, err := ground(w.Latitude, w.Longitude)
+			if err != nil {
+				is.add(w.Line, w.Item, "terrain altitude: %v", err)
+
+				continue
+			}
+			w.Altitude += elevation
+		}
+
+		w.Reference = AltitudeAMSL
+		result = append(result, w)
+	}
+
+	if err := is.err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,222 @@
+package route
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const wplHeader = "QGC WPL 110\n"
+
+// wplLine joins the 12 fields of a mission item with tabs.
+func wplLine(fields ...string) string {
+	return strings.Join(fields, "\t") + "\n"
+}
+
+var (
+	wplHome      = wplLine("0", "1", "0", "16", "0", "0", "0", "0", "21.0000", "105.0000", "10", "1")
+	wplTakeoff   = wplLine("1", "0", "3", "22", "0", "0", "0", "0", "0", "0", "30", "1")
+	wplWaypoint1 = wplLine("2", "0", "3", "16", "0", "0", "0", "0", "21.0010", "105.0010", "50", "1")
+	wplWaypoint2 = wplLine("3", "0", "3", "16", "0", "0", "0", "0", "21.0020", "105.0020", "60", "1")
+)
+
+const validPlan = `{
+	"fileType": "Plan",
+	"mission": {
+		"items": [
+			{"type": "SimpleItem", "command": 22, "frame": 3, "params": [0, 0, 0, null, 0, 0, 30]},
+			{"type": "SimpleItem", "command": 16, "frame": 3, "params": [0, 0, 0, null, 21.001, 105.001, 50]},
+			{"type": "SimpleItem", "command": 178, "frame": 2, "params": [1, 5, -1, 0, 0, 0, 0]},
+			{"type": "SimpleItem", "command": 16, "frame": 0, "params": [0, 0, 0, null, 21.002, 105.002, 60]}
+		],
+		"plannedHomePosition": [21, 105, 10]
+	}
+}`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		format     Format
+		waypoints  int
+		references []AltitudeReference
+		home       bool
+		issue      *Issue // first issue expected, nil for a valid plan
+	}{
+		{
+			name:       "wpl",
+			data:       wplHeader + wplHome + wplTakeoff + wplWaypoint1 + wplWaypoint2,
+			format:     FormatWPL,
+			waypoints:  2,
+			references: []AltitudeReference{AltitudeRelative, AltitudeRelative},
+			home:       true,
+		},
+		{
+			name:       "plan",
+			data:       validPlan,
+			format:     FormatPlan,
+			waypoints:  2,
+			references: []AltitudeReference{AltitudeRelative, AltitudeAMSL},
+			home:       true,
+		},
+		{
+			name:   "wpl without header",
+			data:   wplHome + wplWaypoint1,
+			format: FormatWPL,
+			issue:  &Issue{Line: 1, Message: "expected header QGC WPL 110"},
+		},
+		{
+			name:   "wpl line with missing fields",
+			data:   wplHeader + wplHome + wplWaypoint1 + "3\t0\t3\t16\t0\t0\n" + wplWaypoint2,
+			format: FormatWPL,
+			issue:  &Issue{Line: 4, Message: "has 6 fields, expected 12"},
+		},
+		{
+			name:   "wpl line with a bad latitude",
+			data:   wplHeader + wplHome + wplWaypoint1 + wplLine("3", "0", "3", "16", "0", "0", "0", "0", "north", "105.0020", "60", "1"),
+			format: FormatWPL,
+			issue:  &Issue{Line: 4, Message: `field 9: strconv.ParseFloat: parsing "north": invalid syntax`},
+		},
+		{
+			name:   "wpl local frame",
+			data:   wplHeader + wplHome + wplWaypoint1 + wplLine("3", "0", "1", "16", "0", "0", "0", "0", "21.0020", "105.0020", "60", "1"),
+			format: FormatWPL,
+			issue:  &Issue{Line: 4, Item: 3, Message: "frame 1 of command 16 is not a global frame"},
+		},
+		{
+			name:   "wpl latitude out of range",
+			data:   wplHeader + wplHome + wplWaypoint1 + wplLine("3", "0", "3", "16", "0", "0", "0", "0", "91", "105.0020", "60", "1"),
+			format: FormatWPL,
+			issue:  &Issue{Line: 4, Item: 3, Message: "latitude 91 out of range"},
+		},
+		{
+			name:   "wpl without position commands",
+			data:   wplHeader + wplHome + wplLine("1", "0", "3", "178", "1", "5", "-1", "0", "0", "0", "0", "1"),
+			format: FormatWPL,
+			issue:  &Issue{Message: "route has 0 waypoints, need at least 2"},
+		},
+		{
+			name:   "empty wpl",
+			data:   "",
+			format: FormatWPL,
+			issue:  &Issue{Message: "file is empty"},
+		},
+		{
+			name:   "plan with a syntax error",
+			data:   "{\n\t\"fileType\": \"Plan\",\n\t\"mission\": {,}\n}",
+			format: FormatPlan,
+			issue:  &Issue{Line: 3, Message: "invalid character ',' looking for beginning of object key string"},
+		},
+		{
+			name:   "plan item with a local frame",
+			data:   `{"fileType": "Plan", "mission": {"items": [{"type": "SimpleItem", "command": 16, "frame": 1, "params": [0, 0, 0, 0, 21, 105, 50]}]}}`,
+			format: FormatPlan,
+			issue:  &Issue{Item: 1, Message: "frame 1 of command 16 is not a global frame"},
+		},
+		{
+			name:   "plan complex item",
+			data:   `{"fileType": "Plan", "mission": {"items": [{"type": "ComplexItem", "complexItemType": "survey"}]}}`,
+			format: FormatPlan,
+			issue:  &Issue{Item: 1, Message: "ComplexItem survey is not supported, convert it to waypoints"},
+		},
+		{
+			name:   "empty plan",
+			data:   `{"fileType": "Plan", "mission": {"items": []}}`,
+			format: FormatPlan,
+			issue:  &Issue{Message: "route has 0 waypoints, need at least 2"},
+		},
+		{
+			name:   "not a plan",
+			data:   `{"type": "FeatureCollection"}`,
+			format: FormatPlan,
+			issue:  &Issue{Message: "not a QGroundControl plan"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := Parse([]byte(tt.data), tt.format, AltitudeAMSL)
+
+			if tt.issue != nil {
+				var parseErr *ParseError
+				if !errors.As(err, &parseErr) {
+					t.Fatalf("Parse() error = %v, want a ParseError", err)
+				}
+				if got := parseErr.Issues[0]; got != *tt.issue {
+					t.Errorf("Parse() issue = %+v, want %+v", got, *tt.issue)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(plan.Waypoints) != tt.waypoints {
+				t.Fatalf("Parse() has %d waypoints, want %d", len(plan.Waypoints), tt.waypoints)
+			}
+			for i, w := range plan.Waypoints {
+				if w.Reference != tt.references[i] {
+					t.Errorf("waypoint %d reference = %s, want %s", i, w.Reference, tt.references[i])
+				}
+			}
+			if (plan.Home != nil) != tt.home {
+				t.Errorf("Parse() home = %v, want %v", plan.Home, tt.home)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	plan := &Plan{
+		Home: &Waypoint{Altitude: 10, Reference: AltitudeAMSL},
+		Waypoints: []Waypoint{
+			{Latitude: 21, Longitude: 105, Altitude: 100, Reference: AltitudeAMSL, Line: 2},
+			{Latitude: 21, Longitude: 105, Altitude: 50, Reference: AltitudeRelative, Line: 3},
+			{Latitude: 21, Longitude: 105, Altitude: 30, Reference: AltitudeTerrain, Line: 4},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		ground func(lat, lon float64) (float64, error)
+		want   []float64
+		issue  *Issue
+	}{
+		{
+			name:   "with terrain",
+			ground: func(lat, lon float64) (float64, error) { return 25, nil },
+			want:   []float64{100, 60, 55},
+		},
+		{
+			name:   "without terrain data",
+			ground: func(lat, lon float64) (float64, error) { return 0, errors.New("no terrain data") },
+			issue:  &Issue{Line: 4, Message: "terrain altitude: no terrain data"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waypoints, err := plan.Resolve(nil, tt.ground)
+
+			if tt.issue != nil {
+				var parseErr *ParseError
+				if !errors.As(err, &parseErr) {
+					t.Fatalf("Resolve() error = %v, want a ParseError", err)
+				}
+				if got := parseErr.Issues[0]; got != *tt.issue {
+					t.Errorf("Resolve() issue = %+v, want %+v", got, *tt.issue)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			for i, w := range waypoints {
+				if w.Altitude != tt.want[i] || w.Reference != AltitudeAMSL {
+					t.Errorf("waypoint %d = %v %s, want %v AMSL", i, w.Altitude, w.Reference, tt.want[i])
+				}
+			}
+		})
+	}
+}
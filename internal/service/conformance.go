@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	util "172.21.5.249/air-trans/at-drone/internal/service/util"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type ConformanceSample struct {
+	Timestamp       uint64  `json:"timestamp"` // millisecond
+	Latitude        float64 `json:"latitude"`
+	Longitude       float64 `json:"longitude"`
+	Altitude        float64 `json:"altitude"`    // meter AMSL
+	CrossTrack      float64 `json:"cross_track"` // meter, positive right of the centerline
+	Vertical        float64 `json:"vertical"`    // meter above the centerline
+	AlongTrack      float64 `json:"along_track"` // meter from the first waypoint
+	Ratio           float64 `json:"ratio"`       // 1 on the corridor boundary
+	Segment         int     `json:"segment"`
+	Inside          bool    `json:"inside"`
+	CorridorVersion uint32  `json:"corridor_version"` // effective at the sample
+}
+
+// ConformanceError summarizes the absolute value of an error over the samples.
+type ConformanceError struct {
+	Max  float64 `json:"max"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+}
+
+type ConformanceReport struct {
+	OrderID            string              `json:"order_id"`
+	DroneID            string              `json:"drone_id"`
+	CorridorID         string              `json:"corridor_id"`      // empty when the corridor comes from the order flight route
+	CorridorVersion    uint32              `json:"corridor_version"` // effective at From
+	From               uint64              `json:"from"`             // millisecond
+	To                 uint64              `json:"to"`               // millisecond
+	CrossTrack         ConformanceError    `json:"cross_track"`
+	Vertical           ConformanceError    `json:"vertical"`
+	TimeOutside        uint64              `json:"time_outside"` // millisecond
+	BreachCount        int                 `json:"breach_count"`
+	OutOfOrderSegments []int               `json:"out_of_order_segments"` // segments entered again after a later one was flown
+	Samples            []ConformanceSample `json:"samples"`
+}
+
+// trackHistoryCollections returns the daily track history collections covering [from, to].
+func trackHistoryCollections(from, to uint64) []string {
+	names := []string{}
+	seen := map[string]struct{}{}
+
+	day := uint64(24 * time.Hour / time.Millisecond)
+	for t := from; ; t += day {
+		if t > to {
+			t = to
+		}
+
+		name := util.FindCollectionName(HISTORY_TRACK_PREFIX, t)
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+
+		if t == to {
+			return names
+		}
+	}
+}
+
+// FindTrackHistoryRange returns the track history of a drone in [from, to] across the
+// daily collections, oldest first.
+func (us *MainService) FindTrackHistoryRange(ctx context.Context, droneID string, from, to uint64) ([]*pb.TrackHistory, error) {
+	filter := bson.M{
+		"drone_id":   droneID,
+		"created_at": bson.M{"$gte": from, "$lte": to},
+	}
+
+	rs := []*pb.TrackHistory{}
+	for _, name := range trackHistoryCollections(from, to) {
+		result := []*pb.TrackHistory{}
+		err := db.Collection(name).Find(ctx, filter).Sort("created_at").All(&result)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to find track_history in %s for drone: %s", name, droneID)
+
+			return nil, err
+		}
+
+		rs = append(rs, result...)
+	}
+
+	return rs, nil
+}
+
+// percentile returns the nearest-rank percentile p in [0, 100] of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	return sorted[rank]
+}
+
+func newConformanceError(values []float64) ConformanceError {
+	if len(values) == 0 {
+		return ConformanceError{}
+	}
+
+	sorted := make([]float64, 0, len(values))
+	sum := 0.0
+	for _, v := range values {
+		sorted = append(sorted, math.Abs(v))
+		sum += math.Abs(v)
+	}
+	sort.Float64s(sorted)
+
+	return ConformanceError{
+		Max:  sorted[len(sorted)-1],
+		Mean: sum / float64(len(sorted)),
+		P50:  percentile(sorted, 50),
+		P95:  percentile(sorted, 95),
+		P99:  percentile(sorted, 99),
+	}
+}
+
+// OrderConformance replays the stored positions of the drone of an order through the
+// corridor versions it flew in. The range defaults to the lifetime of the order.
+func (ms *MainService) OrderConformance(ctx context.Context, orderID string, droneID string, from, to uint64) (*ConformanceReport, error) {
+	order, err := ms.FindOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if droneID == "" {
+		droneID = order.DroneID
+	}
+	if from == 0 {
+		from = order.CreatedAt
+	}
+	if to == 0 {
+		to = uint64(time.Now().UnixMilli())
+		if order.Status == pb.AT_ORDER_STATUS_AOS_DELIVERED && order.UpdatedAt > from {
+			to = order.UpdatedAt
+		}
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid time range %d - %d", from, to)
+	}
+
+	corridor, err := ms.FindCorridorAt(ctx, droneID, orderID, from)
+	if err != nil {
+		corridor = corridorFromOrder(order, ms.SvcConfig.ContainmentConfig.Radius)
+	}
+	corridor, err = ms.orderRoute(ctx, corridor, order)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := ms.conformanceVersions(ctx, corridor, order, to)
+	if err != nil {
+		return nil, err
+	}
+
+	histories, err := ms.FindTrackHistoryRange(ctx, droneID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ConformanceReport{
+		OrderID:            orderID,
+		DroneID:            droneID,
+		CorridorID:         corridor.ID,
+		CorridorVersion:    corridor.Version,
+		From:               from,
+		To:                 to,
+		OutOfOrderSegments: []int{},
+		Samples:            []ConformanceSample{},
+	}
+
+	for _, h := range util.ConvertToJSONResponse(ctx, histories) {
+		position := h.LocationJson.GetGeodeticPosition()
+		if position == nil {
+			continue
+		}
+
+		version := versions.at(h.TrackHistory.CreatedAt)
+		lat, lon, altitude := float64(position.Latitude), float64(position.Longitude), ms.historyAltitude(position)
+		dev, err := version.geometry.deviationAt(lat, lon, altitude.Ellipsoid, ms.SvcConfig.ContainmentConfig.Radius, nil)
+		if err != nil {
+			config.PrintDebugLog(ctx, "Skip conformance sample at %d: %v", h.TrackHistory.CreatedAt, err)
+
+			continue
+		}
+
+		report.Samples = append(report.Samples, ConformanceSample{
+			Timestamp:       h.TrackHistory.CreatedAt,
+			Latitude:        lat,
+			Longitude:       lon,
+			Altitude:        altitude.MSL,
+			CrossTrack:      dev.crossTrack,
+			Vertical:        dev.vertical,
+			AlongTrack:      dev.alongTrack,
+			Ratio:           dev.ratio,
+			Segment:         dev.segment,
+			Inside:          dev.ratio <= 1,
+			CorridorVersion: version.corridor.Version,
+		})
+	}
+
+	report.replay()
+
+	config.PrintDebugLog(ctx, "Conformance of order %s: %d samples - %d breaches", orderID, len(report.Samples), report.BreachCount)
+
+	return report, nil
+}
+
+type conformanceVersion struct {
+	corridor *pb.ContainmentCorridor
+	geometry *corridorGeometry
+}
+
+// conformanceVersions lists the corridor effective at the start of the replay followed by
+// its later versions taking over before to, by version.
+type conformanceVersions []conformanceVersion
+
+// at returns the highest version effective at a time in millisecond, the first one before.
+func (vs conformanceVersions) at(t uint64) conformanceVersion {
+	rs := vs[0]
+	for _, v := range vs[1:] {
+		if v.corridor.EffectiveAt <= t && v.corridor.Version > rs.corridor.Version {
+			rs = v
+		}
+	}
+
+	return rs
+}
+
+func (ms *MainService) conformanceVersions(ctx context.Context, corridor *pb.ContainmentCorridor, order *pb.Order, to uint64) (conformanceVersions, error) {
+	perf := newTurnPerformance(ms.SvcConfig.ContainmentConfig)
+
+	geometry, err := newCorridorGeometry(corridor, perf, ms.heights)
+	if err != nil {
+		return nil, err
+	}
+	rs := conformanceVersions{{corridor: corridor, geometry: geometry}}
+
+	// Corridors from the order flight route have no versions
+	if corridor.ID == "" {
+		return rs, nil
+	}
+
+	snapshots, err := ms.FindCorridorVersions(ctx, corridor.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range snapshots {
+		snapshot := &snapshots[i]
+		if snapshot.Version <= corridor.Version || snapshot.Corridor == nil || snapshot.EffectiveAt > to {
+			continue
+		}
+
+		versioned, err := ms.orderRoute(ctx, snapshot.Corridor, order)
+		if err != nil {
+			return nil, err
+		}
+
+		geometry, err := newCorridorGeometry(versioned, perf, ms.heights)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, conformanceVersion{corridor: versioned, geometry: geometry})
+	}
+
+	return rs, nil
+}
+
+// replay aggregates the samples. The time between two samples counts as outside when
+// the first one is, and a segment is out of order when it is flown inside the corridor
+// after a later one of the same corridor version.
+func (r *ConformanceReport) replay() {
+	crossTrack := make([]float64, 0, len(r.Samples))
+	vertical := make([]float64, 0, len(r.Samples))
+	flagged := map[int]struct{}{}
+	furthest := -1
+
+	for i, s := range r.Samples {
+		if i > 0 && s.CorridorVersion != r.Samples[i-1].CorridorVersion {
+			furthest = -1
+		}
+
+		crossTrack = append(crossTrack, s.CrossTrack)
+		vertical = append(vertical, s.Vertical)
+
+		if !s.Inside && (i == 0 || r.Samples[i-1].Inside) {
+			r.BreachCount++
+		}
+		if !s.Inside && i+1 < len(r.Samples) {
+			r.TimeOutside += r.Samples[i+1].Timestamp - s.Timestamp
+		}
+
+		if !s.Inside {
+			continue
+		}
+		if s.Segment < furthest {
+			if _, ok := flagged[s.Segment]; !ok {
+				flagged[s.Segment] = struct{}{}
+				r.OutOfOrderSegments = append(r.OutOfOrderSegments, s.Segment)
+			}
+		}
+		if s.Segment > furthest {
+			furthest = s.Segment
+		}
+	}
+
+	r.CrossTrack = newConformanceError(crossTrack)
+	r.Vertical = newConformanceError(vertical)
+}
+
+// WriteCSV writes the deviation time series, one sample per row.
+func (r *ConformanceReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{
+		"timestamp", "latitude", "longitude", "altitude",
+		"cross_track", "vertical", "along_track", "ratio", "segment", "inside", "corridor_version",
+	})
+	if err != nil {
+		return err
+	}
+
+	format := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	for _, s := range r.Samples {
+		err = writer.Write([]string{
+			strconv.FormatUint(s.Timestamp, 10),
+			format(s.Latitude),
+			format(s.Longitude),
+			format(s.Altitude),
+			format(s.CrossTrack),
+			format(s.Vertical),
+			format(s.AlongTrack),
+			format(s.Ratio),
+			strconv.Itoa(s.Segment),
+			strconv.FormatBool(s.Inside),
+			strconv.FormatUint(uint64(s.CorridorVersion), 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/rs/zerolog/log"
+)
+
+const containmentMonitorTag = "containment-monitor"
+
+type FlightContainmentInfringement struct {
+	Track           *pb.ObjectTrack     `json:"track"`
+	DroneID         string              `json:"drone_id"`
+	Deviation       float64             `json:"deviation"` // meter, as measured
+	Radius          float64             `json:"radius"`    // meter
+	CorridorID      string              `json:"corridor_id"`
+	CorridorVersion uint32              `json:"corridor_version"`
+	State           ContainmentState    `json:"state"`
+	PreviousState   ContainmentState    `json:"previous_state"`
+	Severity        ContainmentSeverity `json:"severity"`
+	Accuracy        TrackAccuracy       `json:"accuracy"`
+	Probability     float64             `json:"probability"` // that the true position is outside the corridor
+	Confidence      float64             `json:"confidence"`  // probability level the state was decided at, 0 for the measured deviation
+	Altitude        TrackAltitude       `json:"altitude"`    // of the track position in both vertical datums
+	Terrain         *TerrainClearance   `json:"terrain"`     // nil without terrain data under the track
+	Timestamp       uint64              `json:"timestamp"`   // millisecond
+}
+
+var containmentStateEvents = map[ContainmentState]NotificationEvent{
+	ContainmentStateWarning:   EventFlightContainmentWarning,
+	ContainmentStateBreach:    EventFlightContainmentInfringement,
+	ContainmentStateRecovered: EventFlightContainmentRecovered,
+}
+
+func (ms *MainService) CheckFlightContainmentAll(ctx context.Context) error {
+	inMemObjectTracks, err := ms.FindAllInMemObjectTrack(ctx, &emptypb.Empty{})
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to get all in_mem object tracks")
+		return err
+	}
+
+	now := uint64(time.Now().UnixMilli())
+
+	// Non-cooperative tracks have no corridor, left to the intrusion check
+	registered, err := ms.registeredDrones(ctx)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to load registered drones")
+	}
+
+	orders := map[string]string{}
+	missions := map[string]*ContainmentResult{}
+	predictions := []*BreachPrediction{}
+	for _, v := range inMemObjectTracks {
+		if _, ok := registered[v.ObjectID]; registered != nil && !ok {
+			continue
+		}
+
+		result, err := ms.CheckFlightContainment(ctx, v)
+		if err != nil {
+			config.PrintDebugLog(ctx, "Skip flight containment for object %s: %v", v.ObjectID, err)
+			result = nil
+		} else {
+			orders[v.ObjectID] = result.OrderID
+			missions[v.ObjectID] = result
+		}
+
+		prediction := ms.predictBreach(v, result, now)
+		if prediction != nil {
+			predictions = append(predictions, prediction)
+		}
+
+		if result != nil {
+			// Keeps the active segment moving between two progress requests
+			ms.activeSegments.Update(result, ms.SvcConfig.ContainmentConfig.Radius, now)
+
+			ms.publishContainmentTransition(ctx, v, result, now)
+			ms.recordCorridorInfringement(ctx, v, result, now)
+		}
+	}
+
+	ms.publishBreachPredictions(ctx, predictions)
+
+	ms.CheckGeofenceAll(ctx, inMemObjectTracks, orders, now)
+
+	ms.CheckSeparationAll(ctx, inMemObjectTracks, now)
+
+	ms.CheckIntrusionAll(ctx, inMemObjectTracks, missions, now)
+
+	// Forget drones no longer tracked, a track missing a few runs keeps its samples
+	if timeout := uint64(ms.SvcConfig.ContainmentConfig.StateTimeout); now > timeout {
+		ms.containment.Prune(now - timeout)
+		ms.geofenceBreaches.Prune(now - timeout)
+		ms.climbRates.Prune(now - timeout)
+		ms.activeSegments.Prune(now - timeout)
+	}
+
+	// After pruning, so that the breaches of the drones forgotten end as well
+	ms.closeInfringements(ctx, now)
+
+	return nil
+}
+
+func (ms *MainService) publishContainmentTransition(ctx context.Context, track *pb.ObjectTrack, result *ContainmentResult, now uint64) {
+	transition := ms.containment.Update(result, now)
+	if transition == nil {
+		return
+	}
+
+	config.PrintDebugLog(ctx, "Drone %s containment state %s -> %s", result.DroneID, transition.PreviousState, transition.State)
+
+	event, ok := containmentStateEvents[transition.State]
+	if !ok {
+		return
+	}
+
+	err := ms.Notifier().Publish(event, FlightContainmentInfringement{
+		Track:           track,
+		DroneID:         result.DroneID,
+		Deviation:       result.Deviation,
+		Radius:          result.Radius,
+		CorridorID:      result.CorridorID,
+		CorridorVersion: result.CorridorVersion,
+		State:           transition.State,
+		PreviousState:   transition.PreviousState,
+		Severity:        transition.Severity,
+		Accuracy:        result.Accuracy,
+		Probability:     result.Probability,
+		Confidence:      transition.Confidence,
+		Altitude:        result.Altitude,
+		Terrain:         result.Terrain,
+		Timestamp:       now,
+	})
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to publish %s for drone: %s", event, result.DroneID)
+	}
+}
+
+// recordCorridorInfringement records the track while the drone is in breach of its corridor.
+func (ms *MainService) recordCorridorInfringement(ctx context.Context, track *pb.ObjectTrack, result *ContainmentResult, now uint64) {
+	if ms.containment.State(result.DroneID) != ContainmentStateBreach {
+		return
+	}
+
+	ms.recordInfringement(ctx, &pb.Infringement{
+		Type:            pb.INFRINGEMENT_TYPE_IT_CORRIDOR,
+		DroneID:         result.DroneID,
+		ObjectTrackID:   result.ObjectTrackID,
+		OrderID:         result.OrderID,
+		CorridorID:      result.CorridorID,
+		CorridorVersion: result.CorridorVersion,
+	}, mslPosition(track.Position, result.Altitude), result.Deviation, breachSeverity(ms.SvcConfig.ContainmentConfig.CriticalFactor, result.Deviation, result.Radius), result.BreachAction, ms.containment.Since(result.DroneID), now)
+}
+
+func (ms *MainService) containmentMonitorJob() {
+	ctx := log.Logger.WithContext(context.Background())
+
+	ms.CheckFlightContainmentAll(ctx)
+}
+
+func (ms *MainService) StartContainmentMonitor(ctx context.Context) error {
+	interval := time.Duration(ms.SvcConfig.ContainmentConfig.Interval) * time.Millisecond
+
+	_, err := ms.scheduler.Every(interval).SingletonMode().Tag(containmentMonitorTag).Do(ms.containmentMonitorJob)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to schedule containment monitor")
+
+		return err
+	}
+
+	// Corridors resolved from active orders are cached until an order changes
+	err = ms.subscribeOrderEvents(ctx)
+	if err != nil {
+		return err
+	}
+
+	ms.scheduler.StartAsync()
+
+	config.PrintInfoLog(ctx, "Started containment monitor every %v", interval)
+
+	return nil
+}
+
+func (ms *MainService) StopContainmentMonitor(ctx context.Context) {
+	ms.scheduler.Stop()
+
+	config.PrintInfoLog(ctx, "Stopped containment monitor")
+}
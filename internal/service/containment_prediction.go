@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	geo "172.21.5.249/air-trans/at-drone/internal/geo"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// maxClimbRateGap is the longest gap between two samples, in second, used to derive a climb rate.
+const maxClimbRateGap = 5.0
+
+type BreachPrediction struct {
+	Track        *pb.ObjectTrack      `json:"track"`
+	DroneID      string               `json:"drone_id"`
+	CorridorID   string               `json:"corridor_id"`    // set when the drone is predicted to leave the corridor
+	GeofenceID   string               `json:"geofence_id"`    // set when the drone is predicted to enter a keep-out geofence
+	TimeToBreach float64              `json:"time_to_breach"` // second
+	Position     *pb.GeodeticPosition `json:"position"`       // predicted position at the breach
+	Altitude     TrackAltitude        `json:"altitude"`       // of the predicted position in both vertical datums
+	Timestamp    uint64               `json:"timestamp"`      // millisecond
+}
+
+func (p *BreachPrediction) key() string {
+	if p.GeofenceID != "" {
+		return "geofence:" + p.GeofenceID
+	}
+	return "corridor:" + p.CorridorID
+}
+
+type climbRate struct {
+	altitude  float64
+	updatedAt uint64
+	rate      float64 // m/s
+	lastSeen  uint64
+}
+
+// climbRateEstimator keeps the vertical speed of each drone, the one reported with the
+// track when there is one and otherwise derived from the altitude of successive samples,
+// as PolarVelocity only carries the horizontal velocity.
+type climbRateEstimator struct {
+	mu     sync.Mutex
+	drones map[string]*climbRate
+}
+
+func newClimbRateEstimator() *climbRateEstimator {
+	return &climbRateEstimator{
+		drones: map[string]*climbRate{},
+	}
+}
+
+// Update records a sample and returns the climb rate of the drone. A measured vertical
+// speed, vz up positive in m/s, replaces the estimate from the altitude difference.
+func (e *climbRateEstimator) Update(droneID string, altitude float64, vz float64, measured bool, updatedAt uint64, now uint64) float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c, ok := e.drones[droneID]
+	if measured {
+		if !ok {
+			c = &climbRate{}
+			e.drones[droneID] = c
+		}
+		c.altitude, c.updatedAt, c.rate, c.lastSeen = altitude, updatedAt, vz, now
+
+		return c.rate
+	}
+	if !ok {
+		e.drones[droneID] = &climbRate{altitude: altitude, updatedAt: updatedAt, lastSeen: now}
+
+		return 0
+	}
+	c.lastSeen = now
+
+	if updatedAt > c.updatedAt {
+		dt := float64(updatedAt-c.updatedAt) / 1000
+
+		c.rate = 0
+		if dt <= maxClimbRateGap {
+			c.rate = (altitude - c.altitude) / dt
+		}
+		c.altitude, c.updatedAt = altitude, updatedAt
+	}
+
+	return c.rate
+}
+
+// Rate returns the last climb rate of the drone, 0 when unknown.
+func (e *climbRateEstimator) Rate(droneID string) float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	c, ok := e.drones[droneID]
+	if !ok {
+		return 0
+	}
+	return c.rate
+}
+
+// trackVerticalSpeed returns the vz of the speed of the track, in m/s up positive, when
+// the track carries one.
+//
+// The object_track schema stores speed.vx/vy/vz, but the generated pb.ObjectTrack of this
+// tree (see pb.ObjectTrack in docs/swagger.json) only has PolarVelocity, so there is no
+// getter to call. The field is looked up by name instead, Speed and Vz following the
+// naming of the protos, and every track without it, which today is every track, falls
+// back to the altitude difference. Replace this with track.GetSpeed().GetVz() once the
+// ObjectTrack proto carries the field.
+func trackVerticalSpeed(track *pb.ObjectTrack) (float64, bool) {
+	m, ok := any(track).(protoreflect.ProtoMessage)
+	if !ok || track == nil {
+		return 0, false
+	}
+
+	return messageVerticalSpeed(m.ProtoReflect())
+}
+
+func messageVerticalSpeed(msg protoreflect.Message) (float64, bool) {
+	speed := fieldByName(msg.Descriptor(), "speed")
+	if speed == nil || speed.Kind() != protoreflect.MessageKind || speed.IsList() || !msg.Has(speed) {
+		return 0, false
+	}
+
+	velocity := msg.Get(speed).Message()
+	vz := fieldByName(velocity.Descriptor(), "vz")
+	if vz == nil || vz.IsList() || (vz.Kind() != protoreflect.DoubleKind && vz.Kind() != protoreflect.FloatKind) {
+		return 0, false
+	}
+
+	return velocity.Get(vz).Float(), true
+}
+
+// fieldByName finds a field regardless of the case of its name, the protos of this
+// system naming fields in CamelCase.
+func fieldByName(d protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := d.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if strings.EqualFold(string(fields.Get(i).Name()), name) {
+			return fields.Get(i)
+		}
+	}
+
+	return nil
+}
+
+func (e *climbRateEstimator) Prune(before uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for id, c := range e.drones {
+		if c.lastSeen < before {
+			delete(e.drones, id)
+		}
+	}
+}
+
+// predictBreach extrapolates the track along its velocity over the configured horizon
+// and returns the first position leaving the corridor or entering a keep-out geofence.
+// Heading is in radian clockwise from north. Limits already breached are left to the
+// containment and geofence checks.
+func (ms *MainService) predictBreach(track *pb.ObjectTrack, result *ContainmentResult, now uint64) *BreachPrediction {
+	cfg := ms.SvcConfig.ContainmentConfig
+	if track == nil || track.Position == nil || cfg.PredictionStep <= 0 {
+		return nil
+	}
+
+	lat, lon, alt := float64(track.Position.Latitude), float64(track.Position.Longitude), ms.trackAltitude(track)
+	vz, measured := trackVerticalSpeed(track)
+	climb := ms.climbRates.Update(track.ObjectID, alt.Ellipsoid, vz, measured, track.UpdatedAt, now)
+	speed := float64(track.GetPolarVelocity().GetSpeed())
+	heading := float64(track.GetPolarVelocity().GetHeading())
+
+	if speed == 0 && climb == 0 {
+		return nil
+	}
+
+	var geometry *corridorGeometry
+	if result != nil && result.Inside {
+		geometry = result.geometry
+	}
+
+	reach := geo.PointBBox(lat, lon).Buffer(speed * float64(cfg.Horizon) / 1000)
+
+	ahead := []*pb.Geofence{}
+	for _, g := range ms.volumes.GeofencesNear(reach) {
+		if g.Type == pb.GEOFENCE_TYPE_GFT_KEEP_OUT && geofencePenetration(g, lat, lon, alt.MSL) <= 0 {
+			ahead = append(ahead, g)
+		}
+	}
+
+	for t := cfg.PredictionStep; t <= cfg.Horizon; t += cfg.PredictionStep {
+		sec := float64(t) / 1000
+		pLat, pLon := geo.Destination(lat, lon, heading, speed*sec)
+		pAlt := alt
+		pAlt.Ellipsoid += climb * sec
+		pAlt.MSL += climb * sec
+
+		prediction := &BreachPrediction{
+			Track:        track,
+			DroneID:      track.ObjectID,
+			TimeToBreach: sec,
+			Position: &pb.GeodeticPosition{
+				Latitude:  float32(pLat),
+				Longitude: float32(pLon),
+				Altitude:  float32(float64(track.Position.Altitude) + climb*sec),
+			},
+			Altitude:  pAlt,
+			Timestamp: now,
+		}
+
+		if geometry != nil && leavesCorridor(geometry, pLat, pLon, pAlt.Ellipsoid, cfg.Radius) {
+			prediction.CorridorID = result.CorridorID
+
+			return prediction
+		}
+
+		for _, g := range ahead {
+			if geofencePenetration(g, pLat, pLon, pAlt.MSL) > 0 {
+				prediction.GeofenceID = g.ID
+
+				return prediction
+			}
+		}
+	}
+
+	return nil
+}
+
+// publishBreachPredictions alerts once per drone and limit when the predicted
+// time to breach drops below the threshold.
+func (ms *MainService) publishBreachPredictions(ctx context.Context, predictions []*BreachPrediction) {
+	threshold := float64(ms.SvcConfig.ContainmentConfig.BreachThreshold) / 1000
+
+	current := map[string]map[string]struct{}{}
+	payloads := map[string]map[string]*BreachPrediction{}
+	for _, p := range predictions {
+		if p.TimeToBreach > threshold {
+			continue
+		}
+
+		if current[p.DroneID] == nil {
+			current[p.DroneID] = map[string]struct{}{}
+			payloads[p.DroneID] = map[string]*BreachPrediction{}
+		}
+		current[p.DroneID][p.key()] = struct{}{}
+		payloads[p.DroneID][p.key()] = p
+	}
+
+	for droneID, keys := range ms.breachPredictions.Replace(current) {
+		for key := range keys {
+			p := payloads[droneID][key]
+
+			config.PrintDebugLog(ctx, "Drone %s predicted to breach %s in %.1f s", droneID, key, p.TimeToBreach)
+
+			err := ms.Notifier().Publish(EventFlightContainmentPredicted, p)
+			if err != nil {
+				config.PrintErrorLog(ctx, err, "Failed to publish predicted breach for drone: %s", droneID)
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// objectTrackDescriptor describes an object track with or without the Speed of the
+// object_track schema.
+func objectTrackDescriptor(t *testing.T, withSpeed bool) protoreflect.MessageDescriptor {
+	double := descriptorpb.FieldDescriptorProto_TYPE_DOUBLE.Enum()
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+
+	track := &descriptorpb.DescriptorProto{
+		Name: proto.String("ObjectTrack"),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			{Name: proto.String("ObjectID"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: optional},
+		},
+	}
+	if withSpeed {
+		track.Field = append(track.Field, &descriptorpb.FieldDescriptorProto{
+			Name: proto.String("Speed"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), Label: optional, TypeName: proto.String(".test.Speed"),
+		})
+	}
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test/object_track.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			track,
+			{
+				Name: proto.String("Speed"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("Vx"), Number: proto.Int32(1), Type: double, Label: optional},
+					{Name: proto.String("Vy"), Number: proto.Int32(2), Type: double, Label: optional},
+					{Name: proto.String("Vz"), Number: proto.Int32(3), Type: double, Label: optional},
+				},
+			},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("protodesc.NewFile() error = %v", err)
+	}
+
+	return file.Messages().ByName("ObjectTrack")
+}
+
+func TestMessageVerticalSpeed(t *testing.T) {
+	tests := []struct {
+		name         string
+		withSpeed    bool
+		vz           *float64 // nil leaves Speed unset
+		want         float64
+		wantMeasured bool
+	}{
+		{name: "schema without speed", withSpeed: false},
+		{name: "speed not reported", withSpeed: true},
+		{name: "climbing", withSpeed: true, vz: proto.Float64(2.5), want: 2.5, wantMeasured: true},
+		{name: "level flight", withSpeed: true, vz: proto.Float64(0), want: 0, wantMeasured: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := dynamicpb.NewMessage(objectTrackDescriptor(t, tt.withSpeed))
+			if tt.vz != nil {
+				speed := msg.Descriptor().Fields().ByName("Speed")
+				velocity := msg.Mutable(speed).Message()
+				velocity.Set(velocity.Descriptor().Fields().ByName("Vz"), protoreflect.ValueOfFloat64(*tt.vz))
+			}
+
+			got, measured := messageVerticalSpeed(msg)
+			if got != tt.want || measured != tt.wantMeasured {
+				t.Errorf("messageVerticalSpeed() = %v, %v, want %v, %v", got, measured, tt.want, tt.wantMeasured)
+			}
+		})
+	}
+}
+
+func TestClimbRateEstimator(t *testing.T) {
+	tests := []struct {
+		name     string
+		vz       float64
+		measured bool
+		want     float64 // after 100 m then 110 m one second apart
+	}{
+		{name: "altitude difference", want: 10},
+		{name: "reported vertical speed", vz: 3, measured: true, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newClimbRateEstimator()
+			e.Update("drone", 100, tt.vz, tt.measured, 1000, 1000)
+
+			got := e.Update("drone", 110, tt.vz, tt.measured, 2000, 2000)
+			if got != tt.want || e.Rate("drone") != tt.want {
+				t.Errorf("Update() = %v, Rate() = %v, want %v", got, e.Rate("drone"), tt.want)
+			}
+		})
+	}
+}
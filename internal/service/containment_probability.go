@@ -0,0 +1,147 @@
+package service
+
+import (
+	"math"
+
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+)
+
+// Simpson intervals across the corridor when integrating over an ellipse cross section
+const probabilitySteps = 64
+
+// TrackAccuracy is the 1-sigma error of a track position, taken as gaussian and
+// independent along each axis.
+type TrackAccuracy struct {
+	Horizontal float64 `json:"horizontal"` // meter per horizontal axis
+	Vertical   float64 `json:"vertical"`   // meter
+}
+
+// trackAccuracy returns the accuracy of a track fused from its sources by inverse variance,
+// the configured default when none of its sources has one. A source without error makes
+// the track exact on that axis.
+func (ms *MainService) trackAccuracy(track *pb.ObjectTrack) TrackAccuracy {
+	cfg := ms.SvcConfig.ContainmentConfig
+
+	found := false
+	horizontal, vertical := 0.0, 0.0 // sums of the inverse variances
+	for _, source := range track.GetSourceTracks() {
+		if source == nil {
+			continue
+		}
+
+		a, ok := cfg.SourceAccuracy[sourceTypeName(source)]
+		if !ok {
+			continue
+		}
+		found = true
+
+		horizontal += 1 / (a.Horizontal * a.Horizontal)
+		vertical += 1 / (a.Vertical * a.Vertical)
+	}
+
+	if !found {
+		return TrackAccuracy{Horizontal: cfg.HorizontalAccuracy, Vertical: cfg.VerticalAccuracy}
+	}
+
+	return TrackAccuracy{Horizontal: 1 / math.Sqrt(horizontal), Vertical: 1 / math.Sqrt(vertical)}
+}
+
+// normalInterval returns the probability that a gaussian variable falls in [lo, hi],
+// a step without deviation.
+func normalInterval(lo, hi, mu, sigma float64) float64 {
+	if hi < lo {
+		return 0
+	}
+	if sigma <= 0 {
+		if mu >= lo && mu <= hi {
+			return 1
+		}
+		return 0
+	}
+
+	return 0.5 * (math.Erf((hi-mu)/(sigma*math.Sqrt2)) - math.Erf((lo-mu)/(sigma*math.Sqrt2)))
+}
+
+// crossSection is a position measured across the corridor at its nearest point, where
+// the corridor is taken as straight.
+type crossSection struct {
+	crossTrack float64 // meter from the centerline
+	vertical   float64 // meter above the middle of the floor and ceiling
+	halfWidth  float64
+	halfHeight float64
+	ellipse    bool
+	accuracy   TrackAccuracy
+}
+
+// outsideProbability returns the probability that the true position is beyond ratio times
+// the limits of the corridor, ratio 1 being its boundary.
+func (s crossSection) outsideProbability(ratio float64) float64 {
+	if ratio <= 0 || s.halfWidth <= 0 || s.halfHeight <= 0 {
+		return 1
+	}
+
+	a, b := ratio*s.halfWidth, ratio*s.halfHeight
+	sigmaX, sigmaZ := s.accuracy.Horizontal, s.accuracy.Vertical
+
+	if !s.ellipse {
+		return 1 - normalInterval(-a, a, s.crossTrack, sigmaX)*normalInterval(-b, b, s.vertical, sigmaZ)
+	}
+
+	// Within an ellipse the vertical extent depends on the lateral offset
+	height := func(x float64) float64 {
+		return b * math.Sqrt(math.Max(0, 1-(x/a)*(x/a)))
+	}
+
+	switch {
+	case sigmaX <= 0:
+		if math.Abs(s.crossTrack) > a {
+			return 1
+		}
+		h := height(s.crossTrack)
+
+		return 1 - normalInterval(-h, h, s.vertical, sigmaZ)
+	case sigmaZ <= 0:
+		if math.Abs(s.vertical) > b {
+			return 1
+		}
+		w := a * math.Sqrt(1-(s.vertical/b)*(s.vertical/b))
+
+		return 1 - normalInterval(-w, w, s.crossTrack, sigmaX)
+	}
+
+	lo, hi := math.Max(-a, s.crossTrack-8*sigmaX), math.Min(a, s.crossTrack+8*sigmaX)
+	if lo >= hi {
+		return 1
+	}
+
+	step := (hi - lo) / probabilitySteps
+	inside := 0.0
+	for k := 0; k <= probabilitySteps; k++ {
+		x := lo + float64(k)*step
+		weight := 2.0
+		if k == 0 || k == probabilitySteps {
+			weight = 1
+		} else if k%2 == 1 {
+			weight = 4
+		}
+
+		u := (x - s.crossTrack) / sigmaX
+		density := math.Exp(-u*u/2) / (sigmaX * math.Sqrt(2*math.Pi))
+		h := height(x)
+		inside += weight * density * normalInterval(-h, h, s.vertical, sigmaZ)
+	}
+	inside *= step / 3
+
+	return math.Max(0, math.Min(1, 1-inside))
+}
+
+// exceeds tells whether the drone is past the threshold in meter on the dominant axis of
+// the result with at least the confidence, or by its measured deviation when the
+// confidence is 0.
+func (r *ContainmentResult) exceeds(threshold, confidence float64) bool {
+	if confidence <= 0 || r.Radius <= 0 {
+		return r.Deviation > threshold
+	}
+
+	return r.section.outsideProbability(threshold/r.Radius) >= confidence
+}
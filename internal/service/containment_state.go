@@ -0,0 +1,266 @@
+package service
+
+import (
+	"strings"
+	"sync"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+)
+
+type ContainmentState string
+
+const (
+	ContainmentStateInside    ContainmentState = "INSIDE"
+	ContainmentStateWarning   ContainmentState = "WARNING"
+	ContainmentStateBreach    ContainmentState = "BREACH"
+	ContainmentStateRecovered ContainmentState = "RECOVERED"
+)
+
+type ContainmentSeverity string
+
+const (
+	ContainmentSeverityInfo     ContainmentSeverity = "INFO"
+	ContainmentSeverityWarning  ContainmentSeverity = "WARNING"
+	ContainmentSeverityMajor    ContainmentSeverity = "MAJOR"
+	ContainmentSeverityCritical ContainmentSeverity = "CRITICAL"
+)
+
+type ContainmentTransition struct {
+	DroneID       string              `json:"drone_id"`
+	PreviousState ContainmentState    `json:"previous_state"`
+	State         ContainmentState    `json:"state"`
+	Severity      ContainmentSeverity `json:"severity"`
+	Confidence    float64             `json:"confidence"` // probability level the samples were counted at, 0 for the measured deviation
+}
+
+// droneContainment keeps the last M samples of a drone. Each sample records
+// whether it fell outside the breach threshold and whether it fell in the warning band.
+type droneContainment struct {
+	state    ContainmentState
+	outside  []bool
+	warning  []bool
+	next     int
+	filled   int
+	since    uint64 // millisecond the state was entered
+	lastSeen uint64
+}
+
+func (d *droneContainment) push(outside, warning bool) {
+	d.outside[d.next] = outside
+	d.warning[d.next] = warning
+	d.next = (d.next + 1) % len(d.outside)
+	if d.filled < len(d.outside) {
+		d.filled++
+	}
+}
+
+func (d *droneContainment) count(samples []bool, value bool) int {
+	n := 0
+	for i := 0; i < d.filled; i++ {
+		if samples[i] == value {
+			n++
+		}
+	}
+	return n
+}
+
+type containmentStateTracker struct {
+	mu     sync.Mutex
+	cfg    config.ContainmentConfig
+	drones map[string]*droneContainment
+}
+
+func newContainmentStateTracker(cfg config.ContainmentConfig) *containmentStateTracker {
+	if cfg.WindowSamples <= 0 {
+		cfg.WindowSamples = 1
+	}
+	if cfg.ConfirmSamples <= 0 || cfg.ConfirmSamples > cfg.WindowSamples {
+		cfg.ConfirmSamples = cfg.WindowSamples
+	}
+
+	return &containmentStateTracker{
+		cfg:    cfg,
+		drones: map[string]*droneContainment{},
+	}
+}
+
+// entry returns the samples of the key, a drone or a drone and a volume, starting inside.
+func (t *containmentStateTracker) entry(key string, now uint64) *droneContainment {
+	d, ok := t.drones[key]
+	if !ok {
+		d = &droneContainment{
+			state:   ContainmentStateInside,
+			outside: make([]bool, t.cfg.WindowSamples),
+			warning: make([]bool, t.cfg.WindowSamples),
+		}
+		t.drones[key] = d
+	}
+	d.lastSeen = now
+
+	return d
+}
+
+// advance moves the state once N of the last M samples confirm a transition, and returns
+// the previous state.
+func (t *containmentStateTracker) advance(d *droneContainment) ContainmentState {
+	confirm := t.cfg.ConfirmSamples
+	previous, next := d.state, d.state
+
+	switch d.state {
+	case ContainmentStateBreach:
+		if d.count(d.outside, false) >= confirm {
+			next = ContainmentStateRecovered
+		}
+	default:
+		if d.count(d.outside, true) >= confirm {
+			next = ContainmentStateBreach
+		} else if d.count(d.warning, true) >= confirm {
+			if d.state != ContainmentStateWarning {
+				next = ContainmentStateWarning
+			}
+		} else if d.count(d.warning, false) >= confirm {
+			next = ContainmentStateInside
+		}
+	}
+
+	if next != previous {
+		d.state, d.since = next, d.lastSeen
+
+		// Start counting afresh so the next transition needs its own confirmation
+		d.next, d.filled = 0, 0
+	}
+
+	return previous
+}
+
+// Update feeds a new evaluation into the drone state machine and returns the
+// transition it caused, or nil when the state did not change.
+//
+// Breach is entered once the deviation exceeds radius+entry_hysteresis on N of the
+// last M samples and left once N of the last M samples are back under
+// radius-exit_hysteresis. The warning band is the warning_buffer meters inside the edge.
+// A sample exceeds a threshold when the true position is past it with the probability
+// of breach_confidence or warning_confidence, given the accuracy of the track.
+func (t *containmentStateTracker) Update(result *ContainmentResult, now uint64) *ContainmentTransition {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	d := t.entry(result.DroneID, now)
+
+	breachThreshold := result.Radius + t.cfg.EntryHysteresis
+	warningThreshold := result.Radius - t.cfg.WarningBuffer
+	if d.state == ContainmentStateBreach {
+		breachThreshold = result.Radius - t.cfg.ExitHysteresis
+	}
+	if d.state == ContainmentStateWarning || d.state == ContainmentStateBreach {
+		warningThreshold -= t.cfg.ExitHysteresis
+	}
+
+	d.push(result.exceeds(breachThreshold, t.cfg.BreachConfidence), result.exceeds(warningThreshold, t.cfg.WarningConfidence))
+
+	previous := t.advance(d)
+	if previous == d.state {
+		return nil
+	}
+
+	return &ContainmentTransition{
+		DroneID:       result.DroneID,
+		PreviousState: previous,
+		State:         d.state,
+		Severity:      t.severity(d.state, result),
+		Confidence:    t.confidence(d.state),
+	}
+}
+
+// Confirm feeds whether a drone is inside a volume without warning band, keyed by the
+// drone and the volume, and returns the confirmed state. Keys back inside are dropped.
+func (t *containmentStateTracker) Confirm(key string, outside bool, now uint64) ContainmentState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	d := t.entry(key, now)
+	d.push(outside, outside)
+	t.advance(d)
+
+	if d.state == ContainmentStateInside && d.count(d.outside, true) == 0 {
+		delete(t.drones, key)
+	}
+
+	return d.state
+}
+
+// Tracked returns the keys with the prefix.
+func (t *containmentStateTracker) Tracked(prefix string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	keys := []string{}
+	for key := range t.drones {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// A breach further than critical_factor times the radius is critical, any other breach is major.
+func breachSeverity(criticalFactor, deviation, radius float64) ContainmentSeverity {
+	if criticalFactor > 0 && deviation > radius*criticalFactor {
+		return ContainmentSeverityCritical
+	}
+	return ContainmentSeverityMajor
+}
+
+func (t *containmentStateTracker) severity(state ContainmentState, result *ContainmentResult) ContainmentSeverity {
+	switch state {
+	case ContainmentStateWarning:
+		return ContainmentSeverityWarning
+	case ContainmentStateBreach:
+		return breachSeverity(t.cfg.CriticalFactor, result.Deviation, result.Radius)
+	default:
+		return ContainmentSeverityInfo
+	}
+}
+
+func (t *containmentStateTracker) confidence(state ContainmentState) float64 {
+	if state == ContainmentStateWarning {
+		return t.cfg.WarningConfidence
+	}
+	return t.cfg.BreachConfidence
+}
+
+func (t *containmentStateTracker) State(droneID string) ContainmentState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	d, ok := t.drones[droneID]
+	if !ok {
+		return ContainmentStateInside
+	}
+	return d.state
+}
+
+// Since returns when the drone, or the drone and volume, entered its state, 0 when it is not tracked.
+func (t *containmentStateTracker) Since(key string) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	d, ok := t.drones[key]
+	if !ok {
+		return 0
+	}
+	return d.since
+}
+
+// Prune drops drones that were not evaluated since the given time.
+func (t *containmentStateTracker) Prune(before uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, d := range t.drones {
+		if d.lastSeen < before {
+			delete(t.drones, id)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	"github.com/qiniu/qmgo"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// dispatchContingency sends the breach action of the infringement to at_command. The
+// command is stored first with the infringement ID as key so that it is sent once per
+// breach, the call itself runs in the background to keep the monitor on schedule.
+func (ms *MainService) dispatchContingency(ctx context.Context, infringement *pb.Infringement, action pb.CONTINGENCY_ACTION) {
+	if action == pb.CONTINGENCY_ACTION_CA_NOTIFY {
+		return
+	}
+
+	now := uint64(time.Now().UnixMilli())
+	command := &pb.ContingencyCommand{
+		ID:         infringement.ID,
+		DroneID:    infringement.DroneID,
+		OrderID:    infringement.OrderID,
+		CorridorID: infringement.CorridorID,
+		GeofenceID: infringement.GeofenceID,
+		Action:     action,
+		Severity:   infringement.Severity,
+		Status:     pb.CONTINGENCY_COMMAND_STATUS_CCMD_PENDING,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	_, err := contingencyCommandColl.InsertOne(ctx, command)
+	if err != nil {
+		if qmgo.IsDup(err) {
+			config.PrintDebugLog(ctx, "Contingency command already dispatched for infringement: %s", infringement.ID)
+
+			return
+		}
+		config.PrintErrorLog(ctx, err, "Failed to insert contingency command: %+v", command)
+
+		return
+	}
+
+	go ms.sendContingencyCommand(command)
+}
+
+func (ms *MainService) sendContingencyCommand(command *pb.ContingencyCommand) {
+	ctx := log.With().Str("contingency-command", command.ID).Logger().WithContext(context.Background())
+
+	timeout := time.Duration(ms.SvcConfig.ContainmentConfig.CommandTimeout) * time.Millisecond
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	command.SentAt = uint64(time.Now().UnixMilli())
+
+	config.PrintInfoLog(ctx, "Dispatch %s to drone %s", command.Action, command.DroneID)
+
+	response, err := ms.DispatchContingencyCommand(callCtx, command)
+
+	status := pb.CONTINGENCY_COMMAND_STATUS_CCMD_ACCEPTED
+	message := ""
+	switch {
+	case err != nil:
+		status = pb.CONTINGENCY_COMMAND_STATUS_CCMD_FAILED
+		message = err.Error()
+	case !response.Accepted:
+		status = pb.CONTINGENCY_COMMAND_STATUS_CCMD_REJECTED
+		message = response.Message
+	default:
+		message = response.Message
+	}
+
+	now := uint64(time.Now().UnixMilli())
+	err = contingencyCommandColl.UpdateId(ctx, command.ID, bson.M{"$set": bson.M{
+		"status":       status,
+		"message":      message,
+		"sent_at":      command.SentAt,
+		"responded_at": now,
+		"updated_at":   now,
+	}})
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to update contingency command by id: %s", command.ID)
+	}
+}
+
+func (ms *MainService) DispatchContingencyCommand(ctx context.Context, command *pb.ContingencyCommand) (*pb.ContingencyCommandResponse, error) {
+	gConn, err := ms.gClient.GetConn(config.SVC_COMMAND)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to get grpc connection")
+
+		return nil, err
+	}
+
+	client := pb.NewContingencyCommandServiceClient(gConn)
+
+	response, err := client.Dispatch(ctx, command)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to dispatch contingency command: %+v", command)
+
+		return nil, err
+	}
+	if response == nil {
+		return nil, fmt.Errorf("empty response from %s", config.SVC_COMMAND)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	util "172.21.5.249/air-trans/at-drone/internal/service/util"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	mongobuilder "go.jtlabs.io/mongo"
+	queryoptions "go.jtlabs.io/query"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var contingencyCommandSchemaBuilder = mongobuilder.NewQueryBuilder(
+	CONTINGENCY_COMMAND,
+	bson.M{
+		"$jsonSchema": bson.M{
+			"bsonType": "object",
+			"properties": bson.M{
+				"_id": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"drone_id": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"order_id": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"corridor_id": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"geofence_id": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"action": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"severity": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"status": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"message": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"sent_at": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"responded_at": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"created_at": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"updated_at": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+			},
+		},
+	},
+	true,
+)
+
+func (us *MainService) SearchContingencyCommand(ctx context.Context, queryOpts queryoptions.Options) (*[]pb.ContingencyCommand, int64) {
+	filter, sorts, skip, limit, projection, err := util.ParseQueryOptions(contingencyCommandSchemaBuilder, queryOpts)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to parse query option")
+
+		return nil, 0
+	}
+
+	query := contingencyCommandColl.Find(ctx, filter)
+
+	count, err := query.Count()
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to count")
+
+		return nil, 0
+	}
+
+	result := []pb.ContingencyCommand{}
+	err = query.Skip(skip).Limit(limit).Sort(sorts...).Select(projection).All(&result)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to query")
+
+		return nil, 0
+	}
+
+	return &result, count
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	util "172.21.5.249/air-trans/at-drone/internal/service/util"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	jsonpatch "github.com/evanphx/json-patch"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (us *MainService) CreateCorridor(ctx context.Context, model *pb.ContainmentCorridor, eventAPI bool) (*pb.ContainmentCorridor, error) {
+	err := us.checkCorridor(ctx, model)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Rejected corridor: %+v", model)
+
+		return nil, err
+	}
+
+	model.CreatedAt = uint64(time.Now().Unix()) * 1000
+	model.UpdatedAt = model.CreatedAt
+	model.Version = 1
+	if model.EffectiveAt == 0 {
+		model.EffectiveAt = model.CreatedAt
+	}
+
+	_, err = corridorColl.InsertOne(ctx, model)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to create corridor: %+v", model)
+
+		return nil, err
+	}
+
+	err = us.recordCorridorVersion(ctx, model, model.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	us.corridors.Clear()
+
+	us.publishEvent(
+		ctx,
+		util.CreatePublishEventData(model, model),
+		fmt.Sprintf("%s.%s.%s.%t.%s", config.SVC_DRONE, config.RSC_CORRIDOR, config.ACT_CREATE, eventAPI, model.ID),
+	)
+
+	return model, err
+}
+
+func (us *MainService) UpdateCorridorByID(ctx context.Context, updatedData *pb.ContainmentCorridor, id string, eventAPI bool) (*pb.ContainmentCorridor, error) {
+	originData := &pb.ContainmentCorridor{}
+	err := corridorColl.Find(ctx, bson.M{"_id": id}).One(originData)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to find corridor  by id: %s", id)
+
+		return nil, err
+	}
+	updatedData.ID = originData.ID
+	err = us.checkCorridor(ctx, updatedData)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Rejected corridor update by id: %s: %+v", id, updatedData)
+
+		return nil, err
+	}
+
+	updatedData.CreatedAt = originData.CreatedAt
+	updatedData.Version = originData.Version + 1
+	updatedData.UpdatedAt = uint64(time.Now().Unix()) * 1000
+	updatedData.EffectiveAt = updatedData.UpdatedAt
+
+	err = us.replaceCorridor(ctx, updatedData, originData.Version, updatedData.UpdatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	us.corridors.Clear()
+
+	us.publishEvent(
+		ctx,
+		util.CreatePublishEventData(originData, updatedData),
+		fmt.Sprintf("%s.%s.%s.%t.%s", config.SVC_DRONE, config.RSC_CORRIDOR, config.ACT_UPDATE, eventAPI, id),
+	)
+
+	return updatedData, err
+}
+
+func (us *MainService) DeleteCorridorByID(ctx context.Context, id string, eventAPI bool) error {
+	data := &pb.ContainmentCorridor{}
+	err := corridorColl.Find(ctx, bson.M{"_id": id}).One(data)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to find corridor  by id: %s", id)
+
+		return err
+	}
+
+	err = corridorColl.Remove(ctx, bson.M{"_id": id})
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to delete corridor  by id: %s", id)
+
+		return err
+	}
+
+	us.volumes.RemoveCorridor(id)
+	us.corridors.Clear()
+
+	us.publishEvent(
+		ctx,
+		util.CreatePublishEventData(data, data),
+		fmt.Sprintf("%s.%s.%s.%t.%s", config.SVC_DRONE, config.RSC_CORRIDOR, config.ACT_DELETE, eventAPI, id),
+	)
+
+	return err
+}
+
+func (us *MainService) PatchCorridorByID(ctx context.Context, patch *jsonpatch.Patch, id string, eventAPI bool) (*pb.ContainmentCorridor, error) {
+	originData := &pb.ContainmentCorridor{}
+	err := corridorColl.Find(ctx, bson.M{"_id": id}).One(originData)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to find corridor  by id: %s", id)
+
+		return nil, err
+	}
+
+	originDataJson, err := json.Marshal(originData)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to decode data")
+
+		return nil, err
+	}
+
+	updatedDataJson, err := patch.Apply(originDataJson)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to apply patch")
+
+		return nil, err
+	}
+
+	updatedData := &pb.ContainmentCorridor{}
+	err = json.Unmarshal(updatedDataJson, updatedData)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to encode data")
+
+		return nil, err
+	}
+	updatedData.ID = originData.ID
+	err = us.checkCorridor(ctx, updatedData)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Rejected corridor patch by id: %s: %+v", id, updatedData)
+
+		return nil, err
+	}
+
+	updatedData.Version = originData.Version + 1
+	updatedData.UpdatedAt = uint64(time.Now().Unix()) * 1000
+	updatedData.EffectiveAt = updatedData.UpdatedAt
+
+	err = us.replaceCorridor(ctx, updatedData, originData.Version, updatedData.UpdatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	us.corridors.Clear()
+
+	us.publishEvent(
+		ctx,
+		util.CreatePublishEventData(originData, updatedData),
+		fmt.Sprintf("%s.%s.%s.%t.%s", config.SVC_DRONE, config.RSC_CORRIDOR, config.ACT_PATCH, eventAPI, id),
+	)
+
+	return updatedData, err
+}
+
+func (us *MainService) FindCorridorByID(ctx context.Context, id string) (*pb.ContainmentCorridor, error) {
+	rs := pb.ContainmentCorridor{}
+	err := corridorColl.Find(ctx, bson.M{"_id": id}).One(&rs)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to find corridor  by id: %s", id)
+
+		return nil, err
+	}
+
+	return &rs, err
+}
+
+func (us *MainService) FindCorridorAll(ctx context.Context) ([]pb.ContainmentCorridor, error) {
+	rs := []pb.ContainmentCorridor{}
+	err := corridorColl.Find(ctx, bson.M{}).All(&rs)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to find corridor  all")
+	}
+
+	return rs, err
+}
+
+/* Find the newest corridor valid now for a drone or its order */
+func (us *MainService) FindActiveCorridor(ctx context.Context, droneID string, orderID string) (*pb.ContainmentCorridor, error) {
+	return us.FindCorridorAt(ctx, droneID, orderID, uint64(time.Now().UnixMilli()))
+}
+
+/* Find the newest corridor valid at a time in millisecond for a drone or its order, at the version effective then */
+func (us *MainService) FindCorridorAt(ctx context.Context, droneID string, orderID string, at uint64) (*pb.ContainmentCorridor, error) {
+	corridor, _, err := us.findCorridorAt(ctx, droneID, orderID, at)
+
+	return corridor, err
+}
+
+// findCorridorAt also returns when the stored version takes over from the returned one,
+// 0 when it is already effective.
+func (us *MainService) findCorridorAt(ctx context.Context, droneID string, orderID string, at uint64) (*pb.ContainmentCorridor, uint64, error) {
+	owner := bson.A{bson.M{"drone_id": droneID}}
+	if orderID != "" {
+		owner = append(owner, bson.M{"order_id": orderID})
+	}
+
+	filter := bson.M{
+		"$and": bson.A{
+			bson.M{"$or": owner},
+			bson.M{"valid_from": bson.M{"$lte": at}},
+			bson.M{"$or": bson.A{
+				bson.M{"valid_to": 0},
+				bson.M{"valid_to": bson.M{"$gte": at}},
+			}},
+		},
+	}
+
+	rs := pb.ContainmentCorridor{}
+	err := corridorColl.Find(ctx, filter).Sort("-updated_at").One(&rs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if rs.EffectiveAt > at {
+		previous, err := us.FindCorridorVersionAt(ctx, rs.ID, at)
+
+		return previous, rs.EffectiveAt, err
+	}
+
+	return &rs, 0, nil
+}
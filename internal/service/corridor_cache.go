@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	"github.com/nats-io/nats.go"
+)
+
+type resolvedCorridor struct {
+	corridor  *pb.ContainmentCorridor
+	err       error
+	expiresAt uint64 // millisecond
+}
+
+// corridorCache keeps the corridor resolved for each drone so that the monitor does not
+// ask at_order and the database for every track on every run. An entry expires after
+// index_refresh, when its corridor stops being valid or a pending version takes over,
+// and all of them are dropped on corridor changes and order events.
+type corridorCache struct {
+	mu     sync.Mutex
+	drones map[string]resolvedCorridor
+}
+
+func newCorridorCache() *corridorCache {
+	return &corridorCache{drones: map[string]resolvedCorridor{}}
+}
+
+func (c *corridorCache) Get(droneID string, now uint64) (*pb.ContainmentCorridor, error, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, ok := c.drones[droneID]
+	if !ok || r.expiresAt <= now {
+		return nil, nil, false
+	}
+
+	return r.corridor, r.err, true
+}
+
+func (c *corridorCache) Put(droneID string, corridor *pb.ContainmentCorridor, err error, expiresAt uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.drones[droneID] = resolvedCorridor{corridor: corridor, err: err, expiresAt: expiresAt}
+}
+
+func (c *corridorCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.drones = map[string]resolvedCorridor{}
+}
+
+// subscribeOrderEvents drops the resolved corridors whenever at_order changes an order,
+// the active order of a drone deciding its corridor.
+func (ms *MainService) subscribeOrderEvents(ctx context.Context) error {
+	subject := fmt.Sprintf("%s.%s.>", config.SVC_ORDER, config.RSC_ORDER)
+
+	_, err := ms.NATSConnection.Subscribe(subject, func(msg *nats.Msg) {
+		config.PrintDebugLog(ctx, "Drop resolved corridors on: %s", msg.Subject)
+
+		ms.corridors.Clear()
+	})
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to subscribe to nats server for: %s", subject)
+	}
+
+	return err
+}
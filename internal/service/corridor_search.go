@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	util "172.21.5.249/air-trans/at-drone/internal/service/util"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	mongobuilder "go.jtlabs.io/mongo"
+	queryoptions "go.jtlabs.io/query"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var corridorSchemaBuilder = mongobuilder.NewQueryBuilder(
+	CORRIDOR,
+	bson.M{
+		"$jsonSchema": bson.M{
+			"bsonType": "object",
+			"properties": bson.M{
+				"_id": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"name": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"order_id": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"drone_id": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"waypoints": bson.M{
+					"bsonType": "Array",
+					"required": true,
+				},
+				"lateral_tolerance": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"vertical_tolerance": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"valid_from": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"valid_to": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"version": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"created_at": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"updated_at": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"created_by": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"updated_by": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"cross_section": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+			},
+		},
+	},
+	true,
+)
+
+func (us *MainService) SearchCorridor(ctx context.Context, queryOpts queryoptions.Options) (*[]pb.ContainmentCorridor, int64) {
+	filter, sorts, skip, limit, projection, err := util.ParseQueryOptions(corridorSchemaBuilder, queryOpts)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to parse query option")
+
+		return nil, 0
+	}
+
+	query := corridorColl.Find(ctx, filter)
+
+	count, err := query.Count()
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to count")
+
+		return nil, 0
+	}
+
+	result := []pb.ContainmentCorridor{}
+	err = query.Skip(skip).Limit(limit).Sort(sorts...).Select(projection).All(&result)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to query")
+
+		return nil, 0
+	}
+
+	return &result, count
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	geo "172.21.5.249/air-trans/at-drone/internal/geo"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+)
+
+type RouteFindingSeverity string
+
+const (
+	RouteFindingError   RouteFindingSeverity = "ERROR" // the corridor is rejected
+	RouteFindingWarning RouteFindingSeverity = "WARNING"
+)
+
+type RouteFindingCode string
+
+const (
+	RouteFindingTooFewWaypoints  RouteFindingCode = "TOO_FEW_WAYPOINTS"
+	RouteFindingZeroLength       RouteFindingCode = "ZERO_LENGTH_SEGMENT"
+	RouteFindingDuplicate        RouteFindingCode = "DUPLICATE_SEGMENT"
+	RouteFindingTurnAngle        RouteFindingCode = "TURN_ANGLE"
+	RouteFindingClimbGradient    RouteFindingCode = "CLIMB_GRADIENT"
+	RouteFindingKeepOutCrossing  RouteFindingCode = "KEEP_OUT_CROSSING"
+	RouteFindingEnduranceExceeds RouteFindingCode = "ENDURANCE"
+)
+
+type RouteFinding struct {
+	Code     RouteFindingCode     `json:"code"`
+	Severity RouteFindingSeverity `json:"severity"`
+	Segment  int                  `json:"segment"` // index of the first waypoint of the segment, or of the turn waypoint, -1 for the whole route
+	Message  string               `json:"message"`
+}
+
+type RouteValidation struct {
+	Valid    bool           `json:"valid"`  // no finding is an error
+	Length   float64        `json:"length"` // meter, horizontal
+	Findings []RouteFinding `json:"findings"`
+}
+
+func (v *RouteValidation) add(code RouteFindingCode, severity RouteFindingSeverity, segment int, format string, args ...interface{}) {
+	v.Findings = append(v.Findings, RouteFinding{
+		Code:     code,
+		Severity: severity,
+		Segment:  segment,
+		Message:  fmt.Sprintf(format, args...),
+	})
+	if severity == RouteFindingError {
+		v.Valid = false
+	}
+}
+
+// CorridorValidationError rejects a corridor with error findings.
+type CorridorValidationError struct {
+	Findings []RouteFinding
+}
+
+func (e *CorridorValidationError) Error() string {
+	for _, f := range e.Findings {
+		if f.Severity == RouteFindingError {
+			return fmt.Sprintf("invalid corridor: %s", f.Message)
+		}
+	}
+
+	return "invalid corridor"
+}
+
+// segmentsIntersect tells whether the segments PQ and AB of the horizontal plane cross or touch.
+func segmentsIntersect(P, Q, A, B geo.Vec) bool {
+	orientation := func(a, b, c geo.Vec) float64 {
+		return (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+	}
+	onSegment := func(a, b, c geo.Vec) bool {
+		return math.Min(a.X, b.X) <= c.X && c.X <= math.Max(a.X, b.X) && math.Min(a.Y, b.Y) <= c.Y && c.Y <= math.Max(a.Y, b.Y)
+	}
+
+	d1, d2 := orientation(A, B, P), orientation(A, B, Q)
+	d3, d4 := orientation(P, Q, A), orientation(P, Q, B)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+
+	return (d1 == 0 && onSegment(A, B, P)) || (d2 == 0 && onSegment(A, B, Q)) ||
+		(d3 == 0 && onSegment(P, Q, A)) || (d4 == 0 && onSegment(P, Q, B))
+}
+
+// segmentCrossesGeofence tells whether the centerline from a to b enters the volume
+// of the geofence. The waypoint altitudes are converted from the frame of the corridor
+// to the AMSL limits of the geofence, the segment being taken at every altitude where
+// the terrain under an AGL corridor is unknown.
+func segmentCrossesGeofence(geofence *pb.Geofence, a, b *pb.CorridorWaypoint, frame altitudeFrame) bool {
+	if geofence.MaxAltitude > geofence.MinAltitude {
+		altA, errA := frame.msl(a.Latitude, a.Longitude, a.Altitude)
+		altB, errB := frame.msl(b.Latitude, b.Longitude, b.Altitude)
+		if errA == nil && errB == nil &&
+			(math.Max(altA, altB) < geofence.MinAltitude || math.Min(altA, altB) > geofence.MaxAltitude) {
+			return false
+		}
+	}
+
+	polygon := geofencePolygon(geofence, a.Latitude, a.Longitude)
+	if len(polygon) < 3 {
+		return false
+	}
+
+	plane := geo.NewLocalTangentPlane(a.Latitude, a.Longitude, 0)
+	P, Q := geo.Vec{}, plane.ToENU(b.Latitude, b.Longitude, 0).Horizontal()
+	if pointInPolygon(P, polygon) || pointInPolygon(Q, polygon) {
+		return true
+	}
+
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		if segmentsIntersect(P, Q, polygon[j], polygon[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// droneRange returns how far the drone of the corridor flies on a full battery in meter,
+// 0 when the endurance check is disabled.
+func (ms *MainService) droneRange(ctx context.Context, droneID string) float64 {
+	cfg := ms.SvcConfig.ContainmentConfig
+	if cfg.Endurance <= 0 {
+		return 0
+	}
+
+	speed := cfg.CruiseSpeed
+	if droneID != "" {
+		drones, err := ms.FindDroneAll(ctx)
+		if err == nil {
+			for i := range drones {
+				if drones[i].DroneID == droneID && drones[i].MaxSpeed > 0 {
+					speed = float64(drones[i].MaxSpeed)
+				}
+			}
+		}
+	}
+
+	return speed * float64(cfg.Endurance) / 1000
+}
+
+// ValidateCorridor checks the route of the corridor, or the flight route of its order
+// when it has no waypoint, against the limits of the aircraft and the active keep-out
+// geofences.
+func (ms *MainService) ValidateCorridor(ctx context.Context, corridor *pb.ContainmentCorridor) (*RouteValidation, error) {
+	if len(corridor.GetWaypoints()) == 0 && corridor.OrderID != "" {
+		order, err := ms.FindOrderByID(ctx, corridor.OrderID)
+		if err != nil {
+			return nil, err
+		}
+
+		fromOrder := corridorFromOrder(order, ms.SvcConfig.ContainmentConfig.Radius)
+		if corridor.DroneID != "" {
+			fromOrder.DroneID = corridor.DroneID
+		}
+		fromOrder.ID = corridor.ID
+		corridor = fromOrder
+	}
+
+	cfg := ms.SvcConfig.ContainmentConfig
+	frame := altitudeFrame{reference: corridor.AltitudeReference, heights: ms.heights}
+	waypoints := corridor.GetWaypoints()
+	result := &RouteValidation{Valid: true, Findings: []RouteFinding{}}
+
+	if len(waypoints) < 2 {
+		result.add(RouteFindingTooFewWaypoints, RouteFindingError, -1, "route has %d waypoints, need at least 2", len(waypoints))
+
+		return result, nil
+	}
+
+	type segmentKey struct{ a, b [3]float64 }
+	seen := map[segmentKey]int{}
+	previousHeading := math.NaN()
+
+	for i := 0; i+1 < len(waypoints); i++ {
+		a, b := waypoints[i], waypoints[i+1]
+
+		horizontal, heading, _, err := geo.Inverse(a.Latitude, a.Longitude, b.Latitude, b.Longitude)
+		if err != nil {
+			horizontal = geo.Distance(a.Latitude, a.Longitude, b.Latitude, b.Longitude)
+			heading = math.NaN()
+		}
+		vertical := b.Altitude - a.Altitude
+		result.Length += horizontal
+
+		if math.Hypot(horizontal, vertical) < cfg.MinSegmentLength {
+			result.add(RouteFindingZeroLength, RouteFindingError, i, "segment %d is %.2f m long, shorter than %.2f m", i, math.Hypot(horizontal, vertical), cfg.MinSegmentLength)
+
+			continue
+		}
+
+		start := [3]float64{a.Latitude, a.Longitude, a.Altitude}
+		end := [3]float64{b.Latitude, b.Longitude, b.Altitude}
+		if j, ok := seen[segmentKey{start, end}]; ok {
+			result.add(RouteFindingDuplicate, RouteFindingWarning, i, "segment %d repeats segment %d", i, j)
+		} else if j, ok := seen[segmentKey{end, start}]; ok {
+			result.add(RouteFindingDuplicate, RouteFindingWarning, i, "segment %d flies segment %d backwards", i, j)
+		} else {
+			seen[segmentKey{start, end}] = i
+		}
+
+		if cfg.MaxClimbGradient > 0 {
+			if horizontal < cfg.MinSegmentLength {
+				result.add(RouteFindingClimbGradient, RouteFindingError, i, "segment %d is vertical, %.1f m over no horizontal distance", i, vertical)
+			} else if gradient := math.Abs(vertical) / horizontal; gradient > cfg.MaxClimbGradient {
+				result.add(RouteFindingClimbGradient, RouteFindingError, i, "segment %d climb gradient %.2f exceeds %.2f", i, gradient, cfg.MaxClimbGradient)
+			}
+		}
+
+		if horizontal >= cfg.MinSegmentLength && !math.IsNaN(heading) {
+			if cfg.MaxTurnAngle > 0 && !math.IsNaN(previousHeading) {
+				turn := math.Abs(math.Remainder(heading-previousHeading, 2*math.Pi))
+				if geo.Deg(turn) > cfg.MaxTurnAngle {
+					result.add(RouteFindingTurnAngle, RouteFindingError, i, "turn of %.0f degree at waypoint %d exceeds %.0f degree", geo.Deg(turn), i, cfg.MaxTurnAngle)
+				}
+			}
+			previousHeading = heading
+		}
+
+		box := geo.PointBBox(a.Latitude, a.Longitude).Extend(b.Latitude, b.Longitude)
+		for _, geofence := range ms.volumes.GeofencesNear(box) {
+			if geofence.Type != pb.GEOFENCE_TYPE_GFT_KEEP_OUT {
+				continue
+			}
+			if segmentCrossesGeofence(geofence, a, b, frame) {
+				result.add(RouteFindingKeepOutCrossing, RouteFindingError, i, "segment %d crosses keep-out geofence %s %s", i, geofence.ID, geofence.Name)
+			}
+		}
+	}
+
+	if maxRange := ms.droneRange(ctx, corridor.DroneID); maxRange > 0 && result.Length > maxRange {
+		result.add(RouteFindingEnduranceExceeds, RouteFindingError, -1, "route is %.0f m long, beyond the %.0f m range of the drone", result.Length, maxRange)
+	}
+
+	config.PrintDebugLog(ctx, "Validated corridor %s: %d findings - valid: %v", corridor.ID, len(result.Findings), result.Valid)
+
+	return result, nil
+}
+
+// checkCorridor rejects the corridor when its validation has an error finding.
+func (ms *MainService) checkCorridor(ctx context.Context, corridor *pb.ContainmentCorridor) error {
+	result, err := ms.ValidateCorridor(ctx, corridor)
+	if err != nil {
+		return err
+	}
+	if !result.Valid {
+		return &CorridorValidationError{Findings: result.Findings}
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	util "172.21.5.249/air-trans/at-drone/internal/service/util"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	"github.com/qiniu/qmgo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrCorridorVersion = errors.New("corridor was changed by another update")
+
+type CorridorAmendment struct {
+	CorridorID      string                  `json:"corridor_id"`
+	DroneID         string                  `json:"drone_id"`
+	OrderID         string                  `json:"order_id"`
+	PreviousVersion uint32                  `json:"previous_version"`
+	Version         uint32                  `json:"version"`
+	EffectiveAt     uint64                  `json:"effective_at"` // millisecond
+	AmendedBy       string                  `json:"amended_by"`
+	Corridor        *pb.ContainmentCorridor `json:"corridor"`
+	Timestamp       uint64                  `json:"timestamp"` // millisecond
+}
+
+func corridorVersionID(id string, version uint32) string {
+	return fmt.Sprintf("%s:%d", id, version)
+}
+
+// recordCorridorVersion stores the immutable snapshot of the corridor at its version.
+func (ms *MainService) recordCorridorVersion(ctx context.Context, corridor *pb.ContainmentCorridor, user string) error {
+	_, err := corridorVersionColl.InsertOne(ctx, &pb.ContainmentCorridorVersion{
+		ID:          corridorVersionID(corridor.ID, corridor.Version),
+		CorridorID:  corridor.ID,
+		Version:     corridor.Version,
+		EffectiveAt: corridor.EffectiveAt,
+		Corridor:    corridor,
+		CreatedAt:   uint64(time.Now().UnixMilli()),
+		CreatedBy:   user,
+	})
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to record corridor %s version %d", corridor.ID, corridor.Version)
+	}
+
+	return err
+}
+
+// replaceCorridor stores the corridor over the version read before the change, failing with
+// ErrCorridorVersion when another update took it first, then records the new version.
+// Corridors stored before versioning have no version.
+func (ms *MainService) replaceCorridor(ctx context.Context, corridor *pb.ContainmentCorridor, previous uint32, user string) error {
+	filter := bson.M{"_id": corridor.ID, "version": previous}
+	if previous == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	err := corridorColl.ReplaceOne(ctx, filter, corridor)
+	if errors.Is(err, qmgo.ErrNoSuchDocuments) {
+		err = ErrCorridorVersion
+	}
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to replace corridor %s version %d", corridor.ID, previous)
+
+		return err
+	}
+
+	return ms.recordCorridorVersion(ctx, corridor, user)
+}
+
+func (ms *MainService) FindCorridorVersions(ctx context.Context, id string) ([]pb.ContainmentCorridorVersion, error) {
+	rs := []pb.ContainmentCorridorVersion{}
+	err := corridorVersionColl.Find(ctx, bson.M{"corridor_id": id}).Sort("version").All(&rs)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to find versions of corridor: %s", id)
+	}
+
+	return rs, err
+}
+
+// FindCorridorVersionAt returns the highest version of the corridor effective at a time
+// in millisecond. A later version effective earlier supersedes a pending one.
+func (ms *MainService) FindCorridorVersionAt(ctx context.Context, id string, at uint64) (*pb.ContainmentCorridor, error) {
+	rs := pb.ContainmentCorridorVersion{}
+	err := corridorVersionColl.Find(ctx, bson.M{
+		"corridor_id":  id,
+		"effective_at": bson.M{"$lte": at},
+	}).Sort("-version").One(&rs)
+	if err != nil {
+		return nil, err
+	}
+	if rs.Corridor == nil {
+		return nil, fmt.Errorf("corridor %s version %d has no snapshot", id, rs.Version)
+	}
+
+	return rs.Corridor, nil
+}
+
+// AmendCorridor re-plans the corridor as a new version taking over at its EffectiveAt,
+// now when unset or in the past. The monitor keeps using the previous version until then.
+func (ms *MainService) AmendCorridor(ctx context.Context, amended *pb.ContainmentCorridor, id string, user string, eventAPI bool) (*pb.ContainmentCorridor, error) {
+	originData := &pb.ContainmentCorridor{}
+	err := corridorColl.Find(ctx, bson.M{"_id": id}).One(originData)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to find corridor  by id: %s", id)
+
+		return nil, err
+	}
+
+	now := uint64(time.Now().UnixMilli())
+
+	amended.ID = originData.ID
+	if amended.DroneID == "" {
+		amended.DroneID = originData.DroneID
+	}
+	if amended.OrderID == "" {
+		amended.OrderID = originData.OrderID
+	}
+	err = ms.checkCorridor(ctx, amended)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Rejected corridor amendment by id: %s: %+v", id, amended)
+
+		return nil, err
+	}
+
+	if amended.EffectiveAt < now {
+		amended.EffectiveAt = now
+	}
+	amended.CreatedAt = originData.CreatedAt
+	amended.CreatedBy = originData.CreatedBy
+	amended.Version = originData.Version + 1
+	amended.UpdatedAt = now
+	amended.UpdatedBy = user
+
+	err = ms.replaceCorridor(ctx, amended, originData.Version, user)
+	if err != nil {
+		return nil, err
+	}
+
+	config.PrintInfoLog(ctx, "Amended corridor %s to version %d effective at %d", id, amended.Version, amended.EffectiveAt)
+
+	ms.corridors.Clear()
+
+	ms.publishEvent(
+		ctx,
+		util.CreatePublishEventData(originData, amended),
+		fmt.Sprintf("%s.%s.%s.%t.%s", config.SVC_DRONE, config.RSC_CORRIDOR, config.ACT_AMEND, eventAPI, id),
+	)
+
+	err = ms.Notifier().Publish(EventCorridorAmended, CorridorAmendment{
+		CorridorID:      id,
+		DroneID:         amended.DroneID,
+		OrderID:         amended.OrderID,
+		PreviousVersion: originData.Version,
+		Version:         amended.Version,
+		EffectiveAt:     amended.EffectiveAt,
+		AmendedBy:       user,
+		Corridor:        amended,
+		Timestamp:       now,
+	})
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to publish %s for corridor: %s", EventCorridorAmended, id)
+	}
+
+	return amended, nil
+}
@@ -1,132 +1,640 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"math"
+	"time"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	geo "172.21.5.249/air-trans/at-drone/internal/geo"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
 )
 
-func rad(x float64) float64 { return x * math.Pi / 180 }
-func latLonToECEF(lat, lon, alt float64) (x, y, z float64) {
-	latR := rad(lat)
-	lonR := rad(lon)
+// segmentParam returns the position of the projection of P on AB, clamped to [0, 1].
+// A zero-length segment projects everything on A.
+func segmentParam(P, A, B geo.Vec) float64 {
+	AB := B.Sub(A)
+	AP := P.Sub(A)
+
+	length2 := AB.Dot(AB)
+	if length2 == 0 {
+		return 0
+	}
 
-	a := 6378137.0
-	e2 := 6.69437999014e-3
+	t := AP.Dot(AB) / length2
 
-	N := a / math.Sqrt(1-math.Sin(latR)*math.Sin(latR)*e2)
+	return math.Max(0, math.Min(1, t))
+}
 
-	x = (N + alt) * math.Cos(latR) * math.Cos(lonR)
-	y = (N + alt) * math.Cos(latR) * math.Sin(lonR)
-	z = (N*(1-e2) + alt) * math.Sin(latR)
+func distancePointToSegment(P, A, B geo.Vec) float64 {
+	t := segmentParam(P, A, B)
+	closest := A.Add(B.Sub(A).Scale(t))
 
-	return
+	return P.Sub(closest).Norm()
 }
 
-func ecefToENU(x, y, z float64, lat0, lon0, x0, y0, z0 float64) (e, n, u float64) {
-	latR := rad(lat0)
-	lonR := rad(lon0)
+type ContainmentLimit string
 
-	dx := x - x0
-	dy := y - y0
-	dz := z - z0
+const (
+	ContainmentLimitLateral ContainmentLimit = "LATERAL"
+	ContainmentLimitFloor   ContainmentLimit = "FLOOR"
+	ContainmentLimitCeiling ContainmentLimit = "CEILING"
+)
 
-	e = -math.Sin(lonR)*dx + math.Cos(lonR)*dy
-	n = -math.Sin(latR)*math.Cos(lonR)*dx -
-		math.Sin(latR)*math.Sin(lonR)*dy +
-		math.Cos(latR)*dz
-	u = math.Cos(latR)*math.Cos(lonR)*dx +
-		math.Cos(latR)*math.Sin(lonR)*dy +
-		math.Sin(latR)*dz
+type corridorDeviation struct {
+	segment       int
+	crossTrack    float64 // meter, positive right of the centerline
+	vertical      float64 // meter above the centerline
+	alongTrack    float64 // meter from the first waypoint
+	halfWidth     float64
+	floor         float64
+	ceiling       float64
+	lateralRatio  float64 // |crossTrack| / halfWidth
+	verticalRatio float64 // signed offset from the middle of floor and ceiling over half the height
+	ratio         float64 // 1 on the corridor boundary
+	routeLength   float64 // meter, horizontal length of the whole path
+	altitude      float64 // meter, of the drone in the altitude reference of the corridor
+}
 
-	return
+// segmentLimits returns the lateral half-width and the altitude band of segment i,
+// falling back to the corridor tolerances around the centerline altitude.
+func segmentLimits(corridor *pb.ContainmentCorridor, i int, radius float64, centerAlt float64) (halfWidth, floor, ceiling float64) {
+	w := corridor.GetWaypoints()[i]
+
+	halfWidth = w.HalfWidth
+	if halfWidth <= 0 {
+		halfWidth = corridor.LateralTolerance
+	}
+	if halfWidth <= 0 {
+		halfWidth = radius
+	}
+
+	if w.AltitudeCeiling > w.AltitudeFloor {
+		return halfWidth, w.AltitudeFloor, w.AltitudeCeiling
+	}
+
+	vertical := corridor.VerticalTolerance
+	if vertical <= 0 {
+		vertical = radius
+	}
+
+	return halfWidth, centerAlt - vertical, centerAlt + vertical
 }
 
-func latLonAltToENU(lat, lon, alt, refLat, refLon, refAlt float64) (e, n, u float64) {
-	x, y, z := latLonToECEF(lat, lon, alt)
-	x0, y0, z0 := latLonToECEF(refLat, refLon, refAlt)
-	return ecefToENU(x, y, z, refLat, refLon, x0, y0, z0)
+// Standard gravity in meter per second squared
+const standardGravity = 9.80665
+
+// turnPerformance gives the radius of the fly-by turns whose waypoint has none, from
+// the defaults applying to the corridors without TurnSpeed or MaxBankAngle.
+type turnPerformance struct {
+	speed     float64 // meter per second
+	bankAngle float64 // degree
 }
 
-type Vec struct {
-	x, y, z float64
+func newTurnPerformance(cfg config.ContainmentConfig) turnPerformance {
+	return turnPerformance{speed: cfg.CruiseSpeed, bankAngle: cfg.MaxBankAngle}
 }
 
-func (a Vec) Sub(b Vec) Vec { return Vec{a.x - b.x, a.y - b.y, a.z - b.z} }
-func (a Vec) Dot(b Vec) float64 {
-	return a.x*b.x + a.y*b.y + a.z*b.z
+// radius returns v² / (g tan φ), 0 when the corridor cannot be turned at a fly-by.
+func (p turnPerformance) radius(corridor *pb.ContainmentCorridor) float64 {
+	speed, bankAngle := corridor.TurnSpeed, corridor.MaxBankAngle
+	if speed <= 0 {
+		speed = p.speed
+	}
+	if bankAngle <= 0 {
+		bankAngle = p.bankAngle
+	}
+	if speed <= 0 || bankAngle <= 0 || bankAngle >= 90 {
+		return 0
+	}
+
+	return speed * speed / (standardGravity * math.Tan(geo.Rad(bankAngle)))
 }
 
-func (a Vec) Norm() float64 {
-	return math.Sqrt(a.Dot(a))
+// corridorLeg is the straight part of a segment, between the turns at its waypoints.
+type corridorLeg struct {
+	from, to geo.Vec // east and north meters, altitude kept as third component
+	along    float64 // meter from the first waypoint to from
 }
 
-func distancePointToSegment(P, A, B Vec) float64 {
-	AB := B.Sub(A)
-	AP := P.Sub(A)
+// corridorTurn is the arc cutting a fly-by waypoint, tangent to both segments.
+type corridorTurn struct {
+	center      geo.Vec
+	radius      float64
+	entry, exit geo.Vec // tangent points, altitude kept as third component
+	start       float64 // angle of entry around center in radian
+	angle       float64 // heading change in radian
+	left        bool    // counterclockwise
+	along       float64 // meter from the first waypoint to entry
+}
+
+func (t *corridorTurn) length() float64 {
+	return t.radius * t.angle
+}
 
-	t := AP.Dot(AB) / AB.Dot(AB)
+// corridorGeometry caches the path of a corridor in the tangent plane of its first
+// waypoint. The centerline follows the straight legs and the arcs of the fly-by waypoints.
+// Altitudes stay in the reference of the corridor, positions are converted to it.
+type corridorGeometry struct {
+	corridor *pb.ContainmentCorridor
+	frame    altitudeFrame
+	plane    *geo.LocalTangentPlane
+	path     []geo.Vec // waypoints in east and north meters, altitude kept as third component
+	legs     []corridorLeg
+	turns    []*corridorTurn // by waypoint, nil for fly-over waypoints and both ends
+	total    float64
+}
 
-	if t <= 0 {
-		return AP.Norm()
-	} else if t >= 1 {
-		return P.Sub(B).Norm()
+func newCorridorGeometry(corridor *pb.ContainmentCorridor, perf turnPerformance, heights heightModels) (*corridorGeometry, error) {
+	waypoints := corridor.GetWaypoints()
+	if len(waypoints) < 2 {
+		return nil, fmt.Errorf("corridor of order %s has %d waypoints, need at least 2", corridor.OrderID, len(waypoints))
 	}
 
-	closest := Vec{
-		A.x + t*AB.x,
-		A.y + t*AB.y,
-		A.z + t*AB.z,
+	g := &corridorGeometry{
+		corridor: corridor,
+		frame:    altitudeFrame{reference: corridor.AltitudeReference, heights: heights},
+		plane:    geo.NewLocalTangentPlane(waypoints[0].Latitude, waypoints[0].Longitude, waypoints[0].Altitude),
+		path:     make([]geo.Vec, 0, len(waypoints)),
+		legs:     make([]corridorLeg, len(waypoints)-1),
+		turns:    make([]*corridorTurn, len(waypoints)),
 	}
-	return P.Sub(closest).Norm()
+
+	for _, w := range waypoints {
+		v := g.plane.ToENU(w.Latitude, w.Longitude, w.Altitude)
+		g.path = append(g.path, geo.Vec{X: v.X, Y: v.Y, Z: w.Altitude})
+	}
+
+	defaultRadius := perf.radius(corridor)
+	for i := 1; i+1 < len(waypoints); i++ {
+		if waypoints[i].Turn != pb.WAYPOINT_TURN_WT_FLY_BY {
+			continue
+		}
+
+		radius := waypoints[i].TurnRadius
+		if radius <= 0 {
+			radius = defaultRadius
+		}
+		g.turns[i] = newCorridorTurn(g.path[i-1], g.path[i], g.path[i+1], radius)
+	}
+
+	for i := range g.legs {
+		leg := corridorLeg{from: g.path[i], to: g.path[i+1], along: g.total}
+		if t := g.turns[i]; t != nil {
+			leg.from = t.exit
+		}
+		if t := g.turns[i+1]; t != nil {
+			leg.to = t.entry
+		}
+		g.legs[i] = leg
+
+		g.total += leg.to.Horizontal().Sub(leg.from.Horizontal()).Norm()
+		if t := g.turns[i+1]; t != nil {
+			t.along = g.total
+			g.total += t.length()
+		}
+	}
+
+	return g, nil
 }
 
-func compute3DDeviation(drone Vec, path []Vec) float64 {
-	minDist := math.MaxFloat64
-	for i := 0; i < len(path)-1; i++ {
-		d := distancePointToSegment(drone, path[i], path[i+1])
-		if d < minDist {
-			minDist = d
+// newCorridorTurn fits the arc of radius tangent to AW and WC at W. The tangent points
+// stay within the first half of each segment, shrinking the radius when needed. It
+// returns nil when the path does not turn or turns back.
+func newCorridorTurn(A, W, C geo.Vec, radius float64) *corridorTurn {
+	in, out := W.Sub(A).Horizontal(), C.Sub(W).Horizontal()
+	inLength, outLength := in.Norm(), out.Norm()
+	if radius <= 0 || inLength == 0 || outLength == 0 {
+		return nil
+	}
+
+	u1, u2 := in.Scale(1/inLength), out.Scale(1/outLength)
+	cross := u1.X*u2.Y - u1.Y*u2.X
+	angle := math.Atan2(math.Abs(cross), u1.Dot(u2))
+	if angle < 1e-6 || angle > math.Pi-1e-3 {
+		return nil
+	}
+
+	d := radius * math.Tan(angle/2)
+	if limit := math.Min(inLength, outLength) / 2; d > limit {
+		d = limit
+		radius = d / math.Tan(angle/2)
+	}
+
+	t := &corridorTurn{radius: radius, angle: angle, left: cross > 0}
+	t.entry = W.Sub(u1.Scale(d)).Horizontal()
+	t.entry.Z = W.Z + (A.Z-W.Z)*d/inLength
+	t.exit = W.Add(u2.Scale(d)).Horizontal()
+	t.exit.Z = W.Z + (C.Z-W.Z)*d/outLength
+
+	normal := geo.Vec{X: -u1.Y, Y: u1.X}
+	if !t.left {
+		normal = normal.Scale(-1)
+	}
+	t.center = t.entry.Horizontal().Add(normal.Scale(radius))
+	t.start = math.Atan2(t.entry.Y-t.center.Y, t.entry.X-t.center.X)
+
+	return t
+}
+
+// project returns the signed offset from the arc, positive right of the centerline, and
+// the fraction of the arc flown abreast P, or false when P is not abreast the arc.
+func (t *corridorTurn) project(P geo.Vec) (crossTrack, fraction float64, ok bool) {
+	CP := P.Horizontal().Sub(t.center)
+
+	swept := math.Atan2(CP.Y, CP.X) - t.start
+	if !t.left {
+		swept = -swept
+	}
+	swept = math.Mod(swept+2*math.Pi, 2*math.Pi)
+	if swept > t.angle {
+		return 0, 0, false
+	}
+
+	crossTrack = CP.Norm() - t.radius
+	if !t.left {
+		crossTrack = -crossTrack
+	}
+
+	return crossTrack, swept / t.angle, true
+}
+
+func (g *corridorGeometry) length() float64 {
+	return g.total
+}
+
+// deviationAt evaluates a geodetic position with a WGS84 altitude against the candidate
+// segments, or all of them when segments is nil. A position outside every candidate is
+// evaluated against the whole path so that the reported deviation stays exact. It fails
+// for AGL corridors where the terrain is unknown.
+func (g *corridorGeometry) deviationAt(lat, lon, alt float64, radius float64, segments []int) (corridorDeviation, error) {
+	drone, err := g.position(lat, lon, alt)
+	if err != nil {
+		return corridorDeviation{}, err
+	}
+
+	dev := compute3DDeviation(drone, g, radius, segments)
+	if segments != nil && dev.ratio > 1 {
+		dev = compute3DDeviation(drone, g, radius, nil)
+	}
+
+	return dev, nil
+}
+
+// position converts a geodetic position with a WGS84 altitude to the tangent plane, the
+// altitude in the reference of the corridor.
+func (g *corridorGeometry) position(lat, lon, alt float64) (geo.Vec, error) {
+	z, err := g.frame.altitude(lat, lon, alt)
+	if err != nil {
+		return geo.Vec{}, err
+	}
+
+	v := g.plane.ToENU(lat, lon, alt)
+
+	return geo.Vec{X: v.X, Y: v.Y, Z: z}, nil
+}
+
+// progressAt evaluates the position against the segments from the active one on and
+// keeps, among those containing it, the one whose along-track distance is the closest to
+// the last one. Where the route crosses or folds back on itself the drone stays on the leg
+// it is flying. Outside all of them it falls back to the least deep segment of the path.
+func (g *corridorGeometry) progressAt(lat, lon, alt float64, radius float64, active int, along float64) (corridorDeviation, error) {
+	drone, err := g.position(lat, lon, alt)
+	if err != nil {
+		return corridorDeviation{}, err
+	}
+
+	best := corridorDeviation{segment: -1}
+	for i := active; i >= 0 && i < len(g.legs); i++ {
+		d := g.segmentDeviation(drone, i, radius)
+		if d.ratio > 1 {
+			continue
+		}
+		if best.segment < 0 || math.Abs(d.alongTrack-along) < math.Abs(best.alongTrack-along) {
+			best = d
 		}
 	}
-	return minDist
+	if best.segment < 0 {
+		best = compute3DDeviation(drone, g, radius, nil)
+	}
+
+	return best, nil
 }
 
-func (ms *MainService) CheckFlightContainment(droneLat, droneLon, droneAlt float64) bool {
+// insideCorridor tells whether the position is within the corridor, false where the
+// terrain under an AGL corridor is unknown.
+func insideCorridor(g *corridorGeometry, lat, lon, alt float64, radius float64) bool {
+	dev, err := g.deviationAt(lat, lon, alt, radius, nil)
 
-	radius := 5.0 // meters
+	return err == nil && dev.ratio <= 1
+}
 
-	// Intended waypoint coordinates in decimal degrees + altitude
-	waypoints := [][]float64{
-		{21.002694, 105.537611, 40.0}, // P1
-		{21.001444, 105.538111, 40.0}, // P2
-		{21.000500, 105.535889, 40.0}, // P3
-		{21.001944, 105.535222, 40.0}, // P4
+// leavesCorridor tells whether the position is out of the corridor, false where the
+// terrain under an AGL corridor is unknown.
+func leavesCorridor(g *corridorGeometry, lat, lon, alt float64, radius float64) bool {
+	dev, err := g.deviationAt(lat, lon, alt, radius, nil)
+
+	return err == nil && dev.ratio > 1
+}
+
+// segmentDeviation evaluates the drone against the leg of segment i and the turns at
+// both its waypoints, keeping the closest.
+func (g *corridorGeometry) segmentDeviation(drone geo.Vec, i int, radius float64) corridorDeviation {
+	flat := drone.Horizontal()
+	leg := g.legs[i]
+	A, B := leg.from.Horizontal(), leg.to.Horizontal()
+
+	t := segmentParam(flat, A, B)
+	crossTrack := distancePointToSegment(flat, A, B)
+	AB, AP := B.Sub(A), flat.Sub(A)
+	if AB.X*AP.Y-AB.Y*AP.X > 0 {
+		crossTrack = -crossTrack
 	}
 
-	// Reference point for ENU origin
-	refLat := waypoints[0][0]
-	refLon := waypoints[0][1]
-	refAlt := waypoints[0][2]
+	best := g.limitDeviation(drone, i, radius, crossTrack, leg.from.Z+t*(leg.to.Z-leg.from.Z), leg.along+t*AB.Norm())
 
-	// Convert intended path to ENU (meters)
-	path := []Vec{}
-	for _, w := range waypoints {
-		e, n, u := latLonAltToENU(w[0], w[1], w[2], refLat, refLon, refAlt)
-		path = append(path, Vec{e, n, u})
+	for _, turn := range []*corridorTurn{g.turns[i], g.turns[i+1]} {
+		if turn == nil {
+			continue
+		}
+
+		crossTrack, f, ok := turn.project(drone)
+		if !ok {
+			continue
+		}
+
+		d := g.limitDeviation(drone, i, radius, crossTrack, turn.entry.Z+f*(turn.exit.Z-turn.entry.Z), turn.along+f*turn.length())
+		if d.ratio < best.ratio {
+			best = d
+		}
 	}
 
-	de, dn, du := latLonAltToENU(droneLat, droneLon, droneAlt, refLat, refLon, refAlt)
-	drone := Vec{de, dn, du}
+	return best
+}
 
-	dev := compute3DDeviation(drone, path)
+// limitDeviation scales the offsets from the centerline by the limits of segment i.
+func (g *corridorGeometry) limitDeviation(drone geo.Vec, i int, radius float64, crossTrack, centerAlt, alongTrack float64) corridorDeviation {
+	d := corridorDeviation{
+		segment:     i,
+		crossTrack:  crossTrack,
+		vertical:    drone.Z - centerAlt,
+		alongTrack:  alongTrack,
+		routeLength: g.length(),
+		altitude:    drone.Z,
+	}
 
-	fmt.Printf("Drone deviation from path centerline: %.3f m\n", dev)
+	d.halfWidth, d.floor, d.ceiling = segmentLimits(g.corridor, i, radius, centerAlt)
+	d.lateralRatio = math.Abs(d.crossTrack) / d.halfWidth
+	d.verticalRatio = (drone.Z - (d.floor+d.ceiling)/2) / ((d.ceiling - d.floor) / 2)
 
-	if dev > radius {
-		fmt.Println("WARNING: Drone is OUTSIDE cylindrical flight containment!")
-		return true
+	if g.corridor.CrossSection == pb.CORRIDOR_CROSS_SECTION_CCS_ELLIPSE {
+		d.ratio = math.Hypot(d.lateralRatio, d.verticalRatio)
 	} else {
-		fmt.Println("Drone is inside containment corridor.")
-		return false
+		d.ratio = math.Max(d.lateralRatio, math.Abs(d.verticalRatio))
+	}
+
+	return d
+}
+
+// compute3DDeviation finds the segment where the drone is the least deep in its limits,
+// among the given segments or all of them when segments is nil. Lateral distances are
+// measured in the horizontal plane and vertical ones on the altitude directly.
+func compute3DDeviation(drone geo.Vec, g *corridorGeometry, radius float64, segments []int) corridorDeviation {
+	best := corridorDeviation{segment: -1, ratio: math.MaxFloat64, routeLength: g.length()}
+
+	if segments == nil {
+		segments = make([]int, len(g.path)-1)
+		for i := range segments {
+			segments[i] = i
+		}
+	}
+
+	for _, i := range segments {
+		d := g.segmentDeviation(drone, i, radius)
+		if d.ratio < best.ratio {
+			best = d
+		}
+	}
+	return best
+}
+
+// exceeded lists the limits the drone is past. Outside an ellipse while inside
+// both axis limits, the dominant axis is reported.
+func (d corridorDeviation) exceeded() []ContainmentLimit {
+	limits := []ContainmentLimit{}
+	if d.lateralRatio > 1 {
+		limits = append(limits, ContainmentLimitLateral)
+	}
+	if d.altitude > d.ceiling {
+		limits = append(limits, ContainmentLimitCeiling)
+	}
+	if d.altitude < d.floor {
+		limits = append(limits, ContainmentLimitFloor)
+	}
+
+	if len(limits) == 0 && d.ratio > 1 {
+		switch {
+		case d.lateralRatio >= math.Abs(d.verticalRatio):
+			limits = append(limits, ContainmentLimitLateral)
+		case d.verticalRatio > 0:
+			limits = append(limits, ContainmentLimitCeiling)
+		default:
+			limits = append(limits, ContainmentLimitFloor)
+		}
+	}
+
+	return limits
+}
+
+type ContainmentResult struct {
+	DroneID         string                `json:"drone_id"`
+	ObjectTrackID   int32                 `json:"object_track_id"`
+	OrderID         string                `json:"order_id"`
+	CorridorID      string                `json:"corridor_id"` // empty when the corridor comes from the order flight route
+	CorridorVersion uint32                `json:"corridor_version"`
+	Position        *pb.GeodeticPosition  `json:"position"`
+	Altitude        TrackAltitude         `json:"altitude"`         // of the position in both vertical datums
+	CrossTrack      float64               `json:"cross_track"`      // meter, positive right of the centerline
+	Vertical        float64               `json:"vertical"`         // meter above the centerline
+	AlongTrack      float64               `json:"along_track"`      // meter from the first waypoint
+	RouteLength     float64               `json:"route_length"`     // meter
+	HalfWidth       float64               `json:"half_width"`       // meter
+	AltitudeFloor   float64               `json:"altitude_floor"`   // meter in the altitude reference of the corridor
+	AltitudeCeiling float64               `json:"altitude_ceiling"` // meter in the altitude reference of the corridor
+	AltitudeRef     pb.ALTITUDE_REFERENCE `json:"altitude_reference"`
+	Terrain         *TerrainClearance     `json:"terrain"`   // nil without terrain data under the drone
+	Deviation       float64               `json:"deviation"` // meter along the dominant axis, scaled by the cross section, as measured
+	Accuracy        TrackAccuracy         `json:"accuracy"`
+	Probability     float64               `json:"probability"`     // that the true position is outside the corridor
+	Radius          float64               `json:"radius"`          // meter, limit of the dominant axis
+	NearestSegment  int                   `json:"nearest_segment"` // index of the first waypoint of the nearest segment
+	Exceeded        []ContainmentLimit    `json:"exceeded"`
+	Inside          bool                  `json:"inside"`
+	BreachAction    pb.CONTINGENCY_ACTION `json:"breach_action"`
+
+	geometry *corridorGeometry
+	section  crossSection
+}
+
+func corridorFromOrder(order *pb.Order, tolerance float64) *pb.ContainmentCorridor {
+	waypoints := []*pb.CorridorWaypoint{}
+	for _, w := range order.GetFlightRoute().GetWaypoints() {
+		waypoints = append(waypoints, &pb.CorridorWaypoint{
+			Latitude:  float64(w.Latitude),
+			Longitude: float64(w.Longitude),
+			Altitude:  float64(w.Altitude),
+		})
+	}
+
+	return &pb.ContainmentCorridor{
+		OrderID:           order.ID,
+		DroneID:           order.DroneID,
+		Waypoints:         waypoints,
+		AltitudeReference: pb.ALTITUDE_REFERENCE_AR_AMSL,
+		LateralTolerance:  tolerance,
+		VerticalTolerance: tolerance,
+	}
+}
+
+// orderRoute gives a corridor without waypoints the flight route of its order, because
+// that is the route the corridor was validated against. The order is loaded unless the
+// given one is already it.
+func (ms *MainService) orderRoute(ctx context.Context, corridor *pb.ContainmentCorridor, order *pb.Order) (*pb.ContainmentCorridor, error) {
+	if len(corridor.GetWaypoints()) > 0 || corridor.OrderID == "" {
+		return corridor, nil
 	}
+
+	if order == nil || order.ID != corridor.OrderID {
+		var err error
+		order, err = ms.FindOrderByID(ctx, corridor.OrderID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	corridor.Waypoints = corridorFromOrder(order, ms.SvcConfig.ContainmentConfig.Radius).Waypoints
+	corridor.AltitudeReference = pb.ALTITUDE_REFERENCE_AR_AMSL
+
+	return corridor, nil
+}
+
+// resolveCorridor returns the corridor defined for the drone or its active order,
+// falling back to the flight route of the active order. The result is cached per drone.
+func (ms *MainService) resolveCorridor(ctx context.Context, droneID string) (*pb.ContainmentCorridor, error) {
+	now := uint64(time.Now().UnixMilli())
+	if corridor, err, ok := ms.corridors.Get(droneID, now); ok {
+		return corridor, err
+	}
+
+	expiresAt := now + uint64(ms.SvcConfig.ContainmentConfig.IndexRefresh)
+	corridor, until, err := ms.findCorridor(ctx, droneID, now)
+	if until > 0 && until < expiresAt {
+		expiresAt = until
+	}
+	ms.corridors.Put(droneID, corridor, err, expiresAt)
+
+	return corridor, err
+}
+
+// findCorridor resolves the corridor of the drone at now, with the time it stops
+// applying, 0 when it has no end.
+func (ms *MainService) findCorridor(ctx context.Context, droneID string, now uint64) (*pb.ContainmentCorridor, uint64, error) {
+	orderID := ""
+	order, orderErr := ms.FindActiveOrderByDroneID(ctx, droneID)
+	if orderErr == nil {
+		orderID = order.ID
+	}
+
+	corridor, pendingAt, err := ms.findCorridorAt(ctx, droneID, orderID, now)
+	if err == nil {
+		corridor, err = ms.orderRoute(ctx, corridor, order)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		until := pendingAt
+		if corridor.ValidTo > 0 && (until == 0 || corridor.ValidTo < until) {
+			until = corridor.ValidTo + 1
+		}
+
+		return corridor, until, nil
+	}
+
+	if orderErr != nil {
+		return nil, 0, orderErr
+	}
+
+	return corridorFromOrder(order, ms.SvcConfig.ContainmentConfig.Radius), 0, nil
+}
+
+func (ms *MainService) CheckFlightContainment(ctx context.Context, track *pb.ObjectTrack) (*ContainmentResult, error) {
+	if track == nil || track.Position == nil {
+		return nil, fmt.Errorf("object track has no position")
+	}
+
+	corridor, err := ms.resolveCorridor(ctx, track.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	geometry, err := ms.volumes.CorridorGeometry(corridor)
+	if err != nil {
+		return nil, err
+	}
+
+	lat, lon, altitude := float64(track.Position.Latitude), float64(track.Position.Longitude), ms.trackAltitude(track)
+	accuracy := ms.trackAccuracy(track)
+	dev, err := geometry.deviationAt(lat, lon, altitude.Ellipsoid, ms.SvcConfig.ContainmentConfig.Radius, ms.volumes.CorridorSegments(corridor.ID, geometry, lat, lon))
+	if err != nil {
+		return nil, fmt.Errorf("corridor %s above ground: %w", corridor.ID, err)
+	}
+
+	radius := dev.halfWidth
+	if math.Abs(dev.verticalRatio) > dev.lateralRatio {
+		radius = (dev.ceiling - dev.floor) / 2
+	}
+
+	section := crossSection{
+		crossTrack: dev.crossTrack,
+		vertical:   dev.altitude - (dev.floor+dev.ceiling)/2,
+		halfWidth:  dev.halfWidth,
+		halfHeight: (dev.ceiling - dev.floor) / 2,
+		ellipse:    corridor.CrossSection == pb.CORRIDOR_CROSS_SECTION_CCS_ELLIPSE,
+		accuracy:   accuracy,
+	}
+
+	result := &ContainmentResult{
+		DroneID:         track.ObjectID,
+		ObjectTrackID:   track.ObjectTrackID,
+		OrderID:         corridor.OrderID,
+		CorridorID:      corridor.ID,
+		CorridorVersion: corridor.Version,
+		Position:        track.Position,
+		Altitude:        altitude,
+		CrossTrack:      dev.crossTrack,
+		Vertical:        dev.vertical,
+		AlongTrack:      dev.alongTrack,
+		RouteLength:     dev.routeLength,
+		HalfWidth:       dev.halfWidth,
+		AltitudeFloor:   dev.floor,
+		AltitudeCeiling: dev.ceiling,
+		AltitudeRef:     corridor.AltitudeReference,
+		Terrain:         ms.terrainClearance(lat, lon, altitude.MSL),
+		Deviation:       dev.ratio * radius,
+		Accuracy:        accuracy,
+		Probability:     section.outsideProbability(1),
+		Radius:          radius,
+		NearestSegment:  dev.segment,
+		Exceeded:        dev.exceeded(),
+		Inside:          dev.ratio <= 1,
+		BreachAction:    corridor.BreachAction,
+		geometry:        geometry,
+		section:         section,
+	}
+
+	config.PrintDebugLog(ctx, "Drone %s cross-track: %.3f m - vertical: %.3f m - along-track: %.3f m - Inside: %v - P(outside): %.3f",
+		result.DroneID, result.CrossTrack, result.Vertical, result.AlongTrack, result.Inside, result.Probability)
+
+	return result, nil
 }
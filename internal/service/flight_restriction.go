@@ -0,0 +1,393 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	geo "172.21.5.249/air-trans/at-drone/internal/geo"
+	util "172.21.5.249/air-trans/at-drone/internal/service/util"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	flightRestrictionTagPrefix = "flight-restriction-"
+	flightRestrictionCategory  = "tfr"
+	restrictionCircleVertices  = 72
+)
+
+type FlightRestrictionNotification struct {
+	Restriction *pb.FlightRestriction `json:"restriction"`
+	Active      bool                  `json:"active"`
+	Intrusions  []GeofenceViolation   `json:"intrusions"` // drones already inside on activation
+	Timestamp   uint64                `json:"timestamp"`  // millisecond
+}
+
+var ErrInvalidFlightRestriction = errors.New("invalid flight restriction")
+
+// validateFlightRestriction rejects a restriction that could never be scheduled or indexed.
+func validateFlightRestriction(model *pb.FlightRestriction, now time.Time) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidFlightRestriction, fmt.Sprintf(format, args...))
+	}
+
+	if model.Shape == pb.RESTRICTION_SHAPE_RS_CIRCLE && model.Radius <= 0 {
+		return invalid("circle restriction needs a positive radius")
+	}
+	if model.Shape == pb.RESTRICTION_SHAPE_RS_POLYGON && len(model.GetVertices()) < 3 {
+		return invalid("polygon restriction has %d vertices, need at least 3", len(model.GetVertices()))
+	}
+
+	// Both zero is a restriction from the ground up, like the geofences
+	if (model.MinAltitude != 0 || model.MaxAltitude != 0) && model.MinAltitude >= model.MaxAltitude {
+		return invalid("min altitude %v is not below max altitude %v", model.MinAltitude, model.MaxAltitude)
+	}
+
+	if model.EndTime > 0 {
+		if model.StartTime >= model.EndTime {
+			return invalid("start time %d is not before end time %d", model.StartTime, model.EndTime)
+		}
+		if now.UnixMilli() >= int64(model.EndTime) {
+			return invalid("end time %d has passed", model.EndTime)
+		}
+	}
+
+	if model.Repeat != "" {
+		_, err := cron.ParseStandard(model.Repeat)
+		if err != nil {
+			return invalid("repeat cron expression %q: %v", model.Repeat, err)
+		}
+		// Duration is unsigned, a negative one is rejected by the binding already
+		if model.Duration == 0 && model.EndTime == 0 {
+			return invalid("repeating restriction needs a duration or an end time")
+		}
+	}
+
+	return nil
+}
+
+func (us *MainService) CreateFlightRestriction(ctx context.Context, model *pb.FlightRestriction, eventAPI bool) (*pb.FlightRestriction, error) {
+	err := validateFlightRestriction(model, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	model.CreatedAt = uint64(time.Now().Unix()) * 1000
+	model.UpdatedAt = model.CreatedAt
+	_, err = flightRestrictionColl.InsertOne(ctx, model)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to create flight restriction: %+v", model)
+
+		return nil, err
+	}
+
+	err = us.scheduleFlightRestriction(ctx, model)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to schedule flight restriction: %s", model.ID)
+
+		// Left stored, the other instances would schedule what this one could not
+		removeErr := flightRestrictionColl.Remove(ctx, bson.M{"_id": model.ID})
+		if removeErr != nil {
+			config.PrintErrorLog(ctx, removeErr, "Failed to delete flight restriction by id: %s", model.ID)
+		}
+
+		return nil, err
+	}
+
+	us.publishEvent(
+		ctx,
+		util.CreatePublishEventData(model, model),
+		fmt.Sprintf("%s.%s.%s.%t.%s", config.SVC_DRONE, config.RSC_FLIGHT_RESTRICTION, config.ACT_CREATE, eventAPI, model.ID),
+	)
+
+	return model, nil
+}
+
+func (us *MainService) FindFlightRestrictionAll(ctx context.Context) ([]pb.FlightRestriction, error) {
+	rs := []pb.FlightRestriction{}
+	err := flightRestrictionColl.Find(ctx, bson.M{}).All(&rs)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to find flight restriction all")
+	}
+
+	return rs, err
+}
+
+/* Approximate the circle by a polygon on the ellipsoid */
+func circleVertices(lat, lon, radius float64, n int) []*pb.GeofenceVertex {
+	vertices := make([]*pb.GeofenceVertex, 0, n)
+	for i := 0; i < n; i++ {
+		vLat, vLon := geo.Destination(lat, lon, 2*math.Pi*float64(i)/float64(n), radius)
+
+		vertices = append(vertices, &pb.GeofenceVertex{
+			Latitude:  vLat,
+			Longitude: vLon,
+		})
+	}
+
+	return vertices
+}
+
+func restrictionGeofence(r *pb.FlightRestriction) *pb.Geofence {
+	vertices := r.GetVertices()
+	if r.Shape == pb.RESTRICTION_SHAPE_RS_CIRCLE {
+		vertices = circleVertices(r.CenterLatitude, r.CenterLongitude, r.Radius, restrictionCircleVertices)
+	}
+
+	return &pb.Geofence{
+		ID:           r.ID,
+		Name:         r.Name,
+		Type:         pb.GEOFENCE_TYPE_GFT_KEEP_OUT,
+		Category:     flightRestrictionCategory,
+		Vertices:     vertices,
+		MinAltitude:  r.MinAltitude,
+		MaxAltitude:  r.MaxAltitude,
+		Active:       true,
+		BreachAction: r.BreachAction,
+	}
+}
+
+// restrictionRegistry remembers the flight restrictions scheduled on this instance.
+type restrictionRegistry struct {
+	mu  sync.Mutex
+	ids map[string]struct{}
+}
+
+// Add returns false when the restriction is already scheduled.
+func (r *restrictionRegistry) Add(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.ids[id]; ok {
+		return false
+	}
+	if r.ids == nil {
+		r.ids = map[string]struct{}{}
+	}
+	r.ids[id] = struct{}{}
+
+	return true
+}
+
+func (r *restrictionRegistry) Remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.ids, id)
+}
+
+// syncFlightRestrictions schedules the flight restrictions not scheduled yet, the ones
+// created through other instances of the service included.
+func (ms *MainService) syncFlightRestrictions(ctx context.Context) error {
+	restrictions, err := ms.FindFlightRestrictionAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range restrictions {
+		err = ms.scheduleFlightRestriction(ctx, &restrictions[i])
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to schedule flight restriction: %s", restrictions[i].ID)
+		}
+	}
+
+	return nil
+}
+
+func (ms *MainService) StartFlightRestrictions(ctx context.Context) error {
+	err := ms.syncFlightRestrictions(ctx)
+	if err != nil {
+		return err
+	}
+
+	config.PrintInfoLog(ctx, "Scheduled flight restrictions")
+
+	return nil
+}
+
+func (ms *MainService) scheduleAt(at time.Time, tag string, jobFun interface{}, params ...interface{}) error {
+	_, err := ms.scheduler.Every(1).Day().StartAt(at).LimitRunsTo(1).Tag(tag).Do(jobFun, params...)
+
+	return err
+}
+
+// currentOccurrence returns the start of the occurrence of a repeating restriction in
+// progress at now, false when none is. Occurrences without Duration last until EndTime.
+func currentOccurrence(r *pb.FlightRestriction, now time.Time) (time.Time, bool) {
+	schedule, err := cron.ParseStandard(r.Repeat)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	// The first fire since Duration ago is still running, cron times are in UTC like the scheduler
+	from := time.UnixMilli(int64(r.StartTime)).Add(-time.Millisecond)
+	if window := now.Add(-time.Duration(r.Duration) * time.Millisecond); r.Duration > 0 && window.After(from) {
+		from = window
+	}
+
+	at := schedule.Next(from.UTC())
+	if at.After(now) {
+		return time.Time{}, false
+	}
+
+	return at, true
+}
+
+// scheduleFlightRestriction switches a one-time restriction on at StartTime and off
+// at EndTime. A repeating one is switched on by its cron expression and off Duration later,
+// right away when an occurrence is in progress.
+// A restriction is scheduled once per instance, a failure leaves it unscheduled.
+func (ms *MainService) scheduleFlightRestriction(ctx context.Context, r *pb.FlightRestriction) error {
+	tag := flightRestrictionTagPrefix + r.ID
+	now := time.Now()
+
+	if !ms.restrictions.Add(r.ID) {
+		return nil
+	}
+	if r.EndTime > 0 && now.UnixMilli() >= int64(r.EndTime) {
+		return nil
+	}
+
+	var err error
+	switch {
+	case r.Repeat != "":
+		_, err = ms.scheduler.Cron(r.Repeat).Tag(tag).Do(ms.activateFlightRestrictionOccurrence, r)
+		if at, ok := currentOccurrence(r, now); err == nil && ok {
+			ms.startFlightRestrictionOccurrence(r, at)
+		}
+	case now.UnixMilli() < int64(r.StartTime):
+		err = ms.scheduleAt(time.UnixMilli(int64(r.StartTime)), tag, ms.activateFlightRestriction, r)
+	default:
+		ms.activateFlightRestriction(r)
+	}
+	if err == nil && r.EndTime > 0 {
+		err = ms.scheduleAt(time.UnixMilli(int64(r.EndTime)), tag, ms.deactivateFlightRestriction, r)
+	}
+	if err != nil {
+		ms.scheduler.RemoveByTag(tag)
+		ms.deactivateFlightRestriction(r)
+		ms.restrictions.Remove(r.ID)
+
+		return err
+	}
+
+	config.PrintDebugLog(ctx, "Scheduled flight restriction: %s", r.ID)
+
+	return nil
+}
+
+func (ms *MainService) activateFlightRestrictionOccurrence(r *pb.FlightRestriction) {
+	now := time.Now()
+
+	if r.EndTime > 0 && now.UnixMilli() >= int64(r.EndTime) {
+		ms.scheduler.RemoveByTag(flightRestrictionTagPrefix + r.ID)
+
+		return
+	}
+	if now.UnixMilli() < int64(r.StartTime) {
+		return
+	}
+
+	ms.startFlightRestrictionOccurrence(r, now)
+}
+
+// startFlightRestrictionOccurrence activates the occurrence started at and schedules its end.
+func (ms *MainService) startFlightRestrictionOccurrence(r *pb.FlightRestriction, at time.Time) {
+	ctx := log.Logger.WithContext(context.Background())
+
+	ms.activateFlightRestriction(r)
+
+	if r.Duration > 0 {
+		err := ms.scheduleAt(at.Add(time.Duration(r.Duration)*time.Millisecond), flightRestrictionTagPrefix+r.ID, ms.deactivateFlightRestriction, r)
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to schedule end of flight restriction: %s", r.ID)
+		}
+	}
+}
+
+func (ms *MainService) activateFlightRestriction(r *pb.FlightRestriction) {
+	ctx := log.Logger.WithContext(context.Background())
+	now := uint64(time.Now().UnixMilli())
+
+	geofence := restrictionGeofence(r)
+	ms.volumes.PutGeofence(geofence, true)
+
+	config.PrintInfoLog(ctx, "Activated flight restriction: %s", r.ID)
+
+	intrusions := []GeofenceViolation{}
+	tracks, err := ms.FindAllInMemObjectTrack(ctx, &emptypb.Empty{})
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to get all in_mem object tracks")
+	}
+
+	// Non-cooperative tracks cannot be told to leave, left to the intrusion check
+	registered, err := ms.registeredDrones(ctx)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to load registered drones")
+	}
+
+	for _, track := range tracks {
+		if track == nil || track.Position == nil {
+			continue
+		}
+		if _, ok := registered[track.ObjectID]; !ok {
+			continue
+		}
+
+		altitude := ms.trackAltitude(track)
+		depth := geofencePenetration(geofence, float64(track.Position.Latitude), float64(track.Position.Longitude), altitude.MSL)
+		if depth <= 0 {
+			continue
+		}
+
+		intrusions = append(intrusions, GeofenceViolation{
+			Track:        track,
+			DroneID:      track.ObjectID,
+			GeofenceID:   geofence.ID,
+			GeofenceName: geofence.Name,
+			Type:         geofence.Type,
+			Penetration:  depth,
+			Altitude:     altitude,
+			Timestamp:    now,
+		})
+	}
+
+	err = ms.Notifier().Publish(EventFlightRestrictionActivated, FlightRestrictionNotification{
+		Restriction: r,
+		Active:      true,
+		Intrusions:  intrusions,
+		Timestamp:   now,
+	})
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to publish activation of flight restriction: %s", r.ID)
+	}
+}
+
+func (ms *MainService) deactivateFlightRestriction(r *pb.FlightRestriction) {
+	ctx := log.Logger.WithContext(context.Background())
+
+	if !ms.volumes.RemoveGeofence(r.ID) {
+		return
+	}
+
+	config.PrintInfoLog(ctx, "Deactivated flight restriction: %s", r.ID)
+
+	err := ms.Notifier().Publish(EventFlightRestrictionDeactivated, FlightRestrictionNotification{
+		Restriction: r,
+		Active:      false,
+		Intrusions:  []GeofenceViolation{},
+		Timestamp:   uint64(time.Now().UnixMilli()),
+	})
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to publish deactivation of flight restriction: %s", r.ID)
+	}
+}
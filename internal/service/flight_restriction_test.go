@@ -0,0 +1,46 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+)
+
+func TestValidateFlightRestriction(t *testing.T) {
+	now := time.UnixMilli(1_700_000_000_000)
+	square := []*pb.GeofenceVertex{
+		{Latitude: 21, Longitude: 105},
+		{Latitude: 21, Longitude: 105.01},
+		{Latitude: 21.01, Longitude: 105.01},
+	}
+
+	tests := []struct {
+		name    string
+		model   *pb.FlightRestriction
+		invalid bool
+	}{
+		{name: "circle", model: &pb.FlightRestriction{Shape: pb.RESTRICTION_SHAPE_RS_CIRCLE, Radius: 500}},
+		{name: "circle without radius", model: &pb.FlightRestriction{Shape: pb.RESTRICTION_SHAPE_RS_CIRCLE}, invalid: true},
+		{name: "polygon", model: &pb.FlightRestriction{Shape: pb.RESTRICTION_SHAPE_RS_POLYGON, Vertices: square}},
+		{name: "polygon with two vertices", model: &pb.FlightRestriction{Shape: pb.RESTRICTION_SHAPE_RS_POLYGON, Vertices: square[:2]}, invalid: true},
+		{name: "altitude band", model: &pb.FlightRestriction{Radius: 500, MinAltitude: 50, MaxAltitude: 120}},
+		{name: "altitude band upside down", model: &pb.FlightRestriction{Radius: 500, MinAltitude: 120, MaxAltitude: 50}, invalid: true},
+		{name: "min altitude without max", model: &pb.FlightRestriction{Radius: 500, MinAltitude: 50}, invalid: true},
+		{name: "start before end", model: &pb.FlightRestriction{Radius: 500, StartTime: 1_700_000_000_000, EndTime: 1_700_000_600_000}},
+		{name: "start after end", model: &pb.FlightRestriction{Radius: 500, StartTime: 1_700_000_600_000, EndTime: 1_700_000_300_000}, invalid: true},
+		{name: "already ended", model: &pb.FlightRestriction{Radius: 500, StartTime: 1_600_000_000_000, EndTime: 1_600_000_600_000}, invalid: true},
+		{name: "repeat", model: &pb.FlightRestriction{Radius: 500, Repeat: "0 8 * * 1-5", Duration: 3_600_000}},
+		{name: "repeat with bad cron", model: &pb.FlightRestriction{Radius: 500, Repeat: "every morning", Duration: 3_600_000}, invalid: true},
+		{name: "repeat without end", model: &pb.FlightRestriction{Radius: 500, Repeat: "0 8 * * 1-5"}, invalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateFlightRestriction(tt.model, now)
+			if tt.invalid != errors.Is(err, ErrInvalidFlightRestriction) || (!tt.invalid && err != nil) {
+				t.Errorf("validateFlightRestriction() error = %v, want invalid %v", err, tt.invalid)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	config "172.21.5.249/air-trans/at-drone/internal/config"
 	util "172.21.5.249/air-trans/at-drone/internal/service/util"
@@ -162,6 +163,25 @@ func (ms *MainService) FindOrderByIDs(ctx context.Context, ids []string) ([]*pb.
 	return rsponse.Order, nil
 }
 
+func (ms *MainService) FindActiveOrderByDroneID(ctx context.Context, droneID string) (*pb.Order, error) {
+	if droneID == "" {
+		return nil, fmt.Errorf("drone id is not be empty")
+	}
+
+	response, err := ms.SearchOrder(ctx, util.CreateSearchOptions(map[string][]string{
+		"drone_id":     {droneID},
+		"order_status": {strconv.Itoa(int(pb.AT_ORDER_STATUS_AOS_IN_DELIVERY))},
+	}, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	if response.TotalCount == 0 || len(response.Order) == 0 {
+		return nil, fmt.Errorf("no active order for drone: %s", droneID)
+	}
+
+	return response.Order[0], nil
+}
+
 // func (ms *MainService) DeleteOrderByID(ctx context.Context, deleteOpt *pb.DeleteOptions) error {
 // 	gConn, err := ms.gClient.GetConn(config.SVC_ORDER)
 // 	if err != nil {
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	util "172.21.5.249/air-trans/at-drone/internal/service/util"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	jsonpatch "github.com/evanphx/json-patch"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (us *MainService) CreateGeofence(ctx context.Context, model *pb.Geofence, eventAPI bool) (*pb.Geofence, error) {
+	model.CreatedAt = uint64(time.Now().Unix()) * 1000
+	model.UpdatedAt = model.CreatedAt
+	_, err := geofenceColl.InsertOne(ctx, model)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to create geofence: %+v", model)
+
+		return nil, err
+	}
+
+	us.volumes.PutGeofence(model, false)
+
+	us.publishEvent(
+		ctx,
+		util.CreatePublishEventData(model, model),
+		fmt.Sprintf("%s.%s.%s.%t.%s", config.SVC_DRONE, config.RSC_GEOFENCE, config.ACT_CREATE, eventAPI, model.ID),
+	)
+
+	return model, err
+}
+
+func (us *MainService) UpdateGeofenceByID(ctx context.Context, updatedData *pb.Geofence, id string, eventAPI bool) (*pb.Geofence, error) {
+	originData := &pb.Geofence{}
+	err := geofenceColl.Find(ctx, bson.M{"_id": id}).One(originData)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to find geofence  by id: %s", id)
+
+		return nil, err
+	}
+	updatedData.ID = originData.ID
+	updatedData.CreatedAt = originData.CreatedAt
+	updatedData.UpdatedAt = uint64(time.Now().Unix()) * 1000
+
+	_, err = geofenceColl.UpsertId(ctx, id, updatedData)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to upsert geofence  by id: %s: %+v", id, updatedData)
+
+		return nil, err
+	}
+
+	us.volumes.PutGeofence(updatedData, false)
+
+	us.publishEvent(
+		ctx,
+		util.CreatePublishEventData(originData, updatedData),
+		fmt.Sprintf("%s.%s.%s.%t.%s", config.SVC_DRONE, config.RSC_GEOFENCE, config.ACT_UPDATE, eventAPI, id),
+	)
+
+	return updatedData, err
+}
+
+func (us *MainService) DeleteGeofenceByID(ctx context.Context, id string, eventAPI bool) error {
+	data := &pb.Geofence{}
+	err := geofenceColl.Find(ctx, bson.M{"_id": id}).One(data)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to find geofence  by id: %s", id)
+
+		return err
+	}
+
+	err = geofenceColl.Remove(ctx, bson.M{"_id": id})
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to delete geofence  by id: %s", id)
+
+		return err
+	}
+
+	us.volumes.RemoveGeofence(id)
+
+	us.publishEvent(
+		ctx,
+		util.CreatePublishEventData(data, data),
+		fmt.Sprintf("%s.%s.%s.%t.%s", config.SVC_DRONE, config.RSC_GEOFENCE, config.ACT_DELETE, eventAPI, id),
+	)
+
+	return err
+}
+
+func (us *MainService) PatchGeofenceByID(ctx context.Context, patch *jsonpatch.Patch, id string, eventAPI bool) (*pb.Geofence, error) {
+	originData := &pb.Geofence{}
+	err := geofenceColl.Find(ctx, bson.M{"_id": id}).One(originData)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to find geofence  by id: %s", id)
+
+		return nil, err
+	}
+
+	originDataJson, err := json.Marshal(originData)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to decode data")
+
+		return nil, err
+	}
+
+	updatedDataJson, err := patch.Apply(originDataJson)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to apply patch")
+
+		return nil, err
+	}
+
+	updatedData := &pb.Geofence{}
+	err = json.Unmarshal(updatedDataJson, updatedData)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to encode data")
+
+		return nil, err
+	}
+	updatedData.ID = originData.ID
+	updatedData.UpdatedAt = uint64(time.Now().Unix()) * 1000
+	_, err = geofenceColl.UpsertId(ctx, id, updatedData)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to upsert geofence  by id: %s: %+v", id, updatedData)
+
+		return nil, err
+	}
+
+	us.volumes.PutGeofence(updatedData, false)
+
+	us.publishEvent(
+		ctx,
+		util.CreatePublishEventData(originData, updatedData),
+		fmt.Sprintf("%s.%s.%s.%t.%s", config.SVC_DRONE, config.RSC_GEOFENCE, config.ACT_PATCH, eventAPI, id),
+	)
+
+	return updatedData, err
+}
+
+func (us *MainService) FindGeofenceByID(ctx context.Context, id string) (*pb.Geofence, error) {
+	rs := pb.Geofence{}
+	err := geofenceColl.Find(ctx, bson.M{"_id": id}).One(&rs)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to find geofence  by id: %s", id)
+
+		return nil, err
+	}
+
+	return &rs, err
+}
+
+func (us *MainService) FindGeofenceAll(ctx context.Context) ([]pb.Geofence, error) {
+	rs := []pb.Geofence{}
+	err := geofenceColl.Find(ctx, bson.M{}).All(&rs)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to find geofence  all")
+	}
+
+	return rs, err
+}
+
+func (us *MainService) FindActiveGeofences(ctx context.Context) ([]pb.Geofence, error) {
+	rs := []pb.Geofence{}
+	err := geofenceColl.Find(ctx, bson.M{"active": true}).All(&rs)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to find active geofence")
+	}
+
+	return rs, err
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	geo "172.21.5.249/air-trans/at-drone/internal/geo"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+)
+
+type GeofenceViolation struct {
+	Track        *pb.ObjectTrack  `json:"track"`
+	DroneID      string           `json:"drone_id"`
+	GeofenceID   string           `json:"geofence_id"`
+	GeofenceName string           `json:"geofence_name"`
+	Type         pb.GEOFENCE_TYPE `json:"type"`
+	Penetration  float64          `json:"penetration"` // meter
+	Altitude     TrackAltitude    `json:"altitude"`    // of the track position in both vertical datums
+	Timestamp    uint64           `json:"timestamp"`   // millisecond
+}
+
+// alertSet remembers the alerts raised per drone in the previous run so that only
+// the first sample of an alert is published.
+type alertSet struct {
+	mu     sync.Mutex
+	alerts map[string]map[string]struct{}
+}
+
+func newAlertSet() *alertSet {
+	return &alertSet{
+		alerts: map[string]map[string]struct{}{},
+	}
+}
+
+// Replace stores the violations of this run and returns the ones that are new.
+func (s *alertSet) Replace(alerts map[string]map[string]struct{}) map[string]map[string]struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added := map[string]map[string]struct{}{}
+	for droneID, ids := range alerts {
+		for id := range ids {
+			if _, ok := s.alerts[droneID][id]; ok {
+				continue
+			}
+			if added[droneID] == nil {
+				added[droneID] = map[string]struct{}{}
+			}
+			added[droneID][id] = struct{}{}
+		}
+	}
+	s.alerts = alerts
+
+	return added
+}
+
+// geofencePolygon converts the vertices to east and north meters around the given point.
+func geofencePolygon(geofence *pb.Geofence, lat, lon float64) []geo.Vec {
+	plane := geo.NewLocalTangentPlane(lat, lon, 0)
+
+	polygon := make([]geo.Vec, 0, len(geofence.GetVertices()))
+	for _, v := range geofence.GetVertices() {
+		polygon = append(polygon, plane.ToENU(v.Latitude, v.Longitude, 0).Horizontal())
+	}
+
+	return polygon
+}
+
+// pointInPolygon casts a ray from P along the east axis and counts the edges it crosses.
+func pointInPolygon(P geo.Vec, polygon []geo.Vec) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		A, B := polygon[i], polygon[j]
+		if (A.Y > P.Y) != (B.Y > P.Y) && P.X < (B.X-A.X)*(P.Y-A.Y)/(B.Y-A.Y)+A.X {
+			inside = !inside
+		}
+	}
+
+	return inside
+}
+
+func distanceToPolygonEdge(P geo.Vec, polygon []geo.Vec) float64 {
+	minDist := math.MaxFloat64
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		if polygon[i] == polygon[j] {
+			continue
+		}
+		minDist = math.Min(minDist, distancePointToSegment(P, polygon[j], polygon[i]))
+	}
+
+	return minDist
+}
+
+// geofencePenetration returns how deep the position is inside the volume, negative
+// values are the distance to the volume when outside. Geofence altitudes are AMSL.
+func geofencePenetration(geofence *pb.Geofence, lat, lon, alt float64) float64 {
+	polygon := geofencePolygon(geofence, lat, lon)
+	if len(polygon) < 3 {
+		return math.Inf(-1)
+	}
+
+	P := geo.Vec{}
+	horizontal := distanceToPolygonEdge(P, polygon)
+	if !pointInPolygon(P, polygon) {
+		horizontal = -horizontal
+	}
+
+	// Without a band the geofence extends from the ground up
+	vertical := math.Inf(1)
+	if geofence.MaxAltitude > geofence.MinAltitude {
+		vertical = math.Min(alt-geofence.MinAltitude, geofence.MaxAltitude-alt)
+	}
+
+	if horizontal >= 0 && vertical >= 0 {
+		return math.Min(horizontal, vertical)
+	}
+
+	return -math.Hypot(math.Min(horizontal, 0), math.Min(vertical, 0))
+}
+
+// checkGeofences returns the geofences violated by the position with their penetration
+// depth, msl in meter AMSL. A keep-out geofence is violated inside its volume, keep-in
+// geofences are violated when the position is outside all of them, in which case the
+// nearest one is reported.
+func checkGeofences(lat, lon, msl float64, geofences []*pb.Geofence) map[*pb.Geofence]float64 {
+	violations := map[*pb.Geofence]float64{}
+
+	var nearestKeepIn *pb.Geofence
+	nearestDepth := math.Inf(-1)
+	for _, g := range geofences {
+		depth := geofencePenetration(g, lat, lon, msl)
+
+		switch g.Type {
+		case pb.GEOFENCE_TYPE_GFT_KEEP_OUT:
+			if depth > 0 {
+				violations[g] = depth
+			}
+		case pb.GEOFENCE_TYPE_GFT_KEEP_IN:
+			if depth >= 0 {
+				nearestKeepIn = nil
+				nearestDepth = math.Inf(1)
+			} else if depth > nearestDepth {
+				nearestKeepIn = g
+				nearestDepth = depth
+			}
+		}
+	}
+
+	if nearestKeepIn != nil {
+		violations[nearestKeepIn] = -nearestDepth
+	}
+
+	return violations
+}
+
+// geofenceBreachKey identifies the confirmation of a drone inside a geofence.
+func geofenceBreachKey(droneID, geofenceID string) string {
+	return droneID + "/" + geofenceID
+}
+
+// CheckGeofenceAll publishes the new geofence violations of the registered drones and
+// records the infringements once confirmed, orders maps the drones to the order they are flying.
+func (ms *MainService) CheckGeofenceAll(ctx context.Context, tracks []*pb.ObjectTrack, orders map[string]string, now uint64) {
+	registered, err := ms.registeredDrones(ctx)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to load registered drones")
+
+		return
+	}
+
+	current := map[string]map[string]struct{}{}
+	payloads := map[string]map[string]GeofenceViolation{}
+	for _, track := range tracks {
+		if track == nil || track.Position == nil {
+			continue
+		}
+		if _, ok := registered[track.ObjectID]; !ok {
+			continue
+		}
+
+		lat, lon, altitude := float64(track.Position.Latitude), float64(track.Position.Longitude), ms.trackAltitude(track)
+		near := ms.volumes.GeofencesNear(geo.PointBBox(lat, lon))
+		for g, depth := range checkGeofences(lat, lon, altitude.MSL, near) {
+			if current[track.ObjectID] == nil {
+				current[track.ObjectID] = map[string]struct{}{}
+				payloads[track.ObjectID] = map[string]GeofenceViolation{}
+			}
+			current[track.ObjectID][g.ID] = struct{}{}
+			payloads[track.ObjectID][g.ID] = GeofenceViolation{
+				Track:        track,
+				DroneID:      track.ObjectID,
+				GeofenceID:   g.ID,
+				GeofenceName: g.Name,
+				Type:         g.Type,
+				Penetration:  depth,
+				Altitude:     altitude,
+				Timestamp:    now,
+			}
+
+			// Contingency commands wait for N of the last M samples inside the geofence
+			key := geofenceBreachKey(track.ObjectID, g.ID)
+			if ms.geofenceBreaches.Confirm(key, true, now) != ContainmentStateBreach {
+				continue
+			}
+
+			ms.recordInfringement(ctx, &pb.Infringement{
+				Type:          pb.INFRINGEMENT_TYPE_IT_GEOFENCE,
+				DroneID:       track.ObjectID,
+				ObjectTrackID: track.ObjectTrackID,
+				OrderID:       orders[track.ObjectID],
+				GeofenceID:    g.ID,
+			}, mslPosition(track.Position, altitude), depth, breachSeverity(ms.SvcConfig.ContainmentConfig.CriticalFactor, depth, ms.SvcConfig.ContainmentConfig.Radius), g.BreachAction, ms.geofenceBreaches.Since(key), now)
+		}
+
+		// Geofences left by the drone count samples towards recovery
+		prefix := geofenceBreachKey(track.ObjectID, "")
+		for _, key := range ms.geofenceBreaches.Tracked(prefix) {
+			if _, ok := current[track.ObjectID][strings.TrimPrefix(key, prefix)]; !ok {
+				ms.geofenceBreaches.Confirm(key, false, now)
+			}
+		}
+	}
+
+	for droneID, geofenceIDs := range ms.geofenceViolations.Replace(current) {
+		for geofenceID := range geofenceIDs {
+			config.PrintDebugLog(ctx, "Drone %s violated geofence %s", droneID, geofenceID)
+
+			err := ms.Notifier().Publish(EventGeofenceViolated, payloads[droneID][geofenceID])
+			if err != nil {
+				config.PrintErrorLog(ctx, err, "Failed to publish geofence violation for drone: %s", droneID)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	util "172.21.5.249/air-trans/at-drone/internal/service/util"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	mongobuilder "go.jtlabs.io/mongo"
+	queryoptions "go.jtlabs.io/query"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var geofenceSchemaBuilder = mongobuilder.NewQueryBuilder(
+	GEOFENCE,
+	bson.M{
+		"$jsonSchema": bson.M{
+			"bsonType": "object",
+			"properties": bson.M{
+				"_id": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"name": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"type": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"category": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"vertices": bson.M{
+					"bsonType": "Array",
+					"required": true,
+				},
+				"min_altitude": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"max_altitude": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"active": bson.M{
+					"bsonType": "bool",
+					"required": true,
+				},
+				"created_at": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"updated_at": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"created_by": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"updated_by": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+			},
+		},
+	},
+	true,
+)
+
+func (us *MainService) SearchGeofence(ctx context.Context, queryOpts queryoptions.Options) (*[]pb.Geofence, int64) {
+	filter, sorts, skip, limit, projection, err := util.ParseQueryOptions(geofenceSchemaBuilder, queryOpts)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to parse query option")
+
+		return nil, 0
+	}
+
+	query := geofenceColl.Find(ctx, filter)
+
+	count, err := query.Count()
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to count")
+
+		return nil, 0
+	}
+
+	result := []pb.Geofence{}
+	err = query.Skip(skip).Limit(limit).Sort(sorts...).Select(projection).All(&result)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to query")
+
+		return nil, 0
+	}
+
+	return &result, count
+}
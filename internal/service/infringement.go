@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	"github.com/google/uuid"
+	"github.com/qiniu/qmgo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type InfringementCount struct {
+	Key   string `json:"key" bson:"_id"`
+	Count int64  `json:"count" bson:"count"`
+}
+
+// InfringementStats counts the infringements started in a time range. The route
+// is the corridor, or the order flight route when no corridor was defined.
+type InfringementStats struct {
+	ByDrone []InfringementCount `json:"by_drone" bson:"by_drone"`
+	ByRoute []InfringementCount `json:"by_route" bson:"by_route"`
+	ByDay   []InfringementCount `json:"by_day" bson:"by_day"` // UTC day as YYYY-MM-DD
+}
+
+type openInfringement struct {
+	record   *pb.Infringement
+	interval uint64 // millisecond between samples
+}
+
+// infringementRecorder follows the infringements in progress between two monitor runs.
+// An infringement lasts as long as the confirmed breach of its drone and volume, a run
+// without sample leaves it open.
+// Samples are recorded at the configured interval, once max_samples is reached every
+// other sample is dropped and the interval doubled so the samples span the whole infringement.
+type infringementRecorder struct {
+	mu         sync.Mutex
+	maxSamples int
+	interval   uint64
+	open       map[string]*openInfringement
+}
+
+func newInfringementRecorder(cfg config.ContainmentConfig) *infringementRecorder {
+	maxSamples := cfg.MaxSamples
+	if maxSamples < 2 {
+		maxSamples = 2
+	}
+
+	return &infringementRecorder{
+		maxSamples: maxSamples,
+		interval:   uint64(cfg.SampleInterval),
+		open:       map[string]*openInfringement{},
+	}
+}
+
+func infringementKey(record *pb.Infringement) string {
+	if record.Type == pb.INFRINGEMENT_TYPE_IT_GEOFENCE {
+		return record.DroneID + "/geofence/" + record.GeofenceID
+	}
+	return record.DroneID + "/corridor/" + record.CorridorID
+}
+
+// infringementID derives the ID from the drone, the volume and the start of the breach so
+// that the same breach is stored, and its contingency command sent, only once.
+func infringementID(record *pb.Infringement, start uint64) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("%s/%d", infringementKey(record), start))).String()
+}
+
+// Record adds the position to the infringement in progress for the same drone and
+// volume, whatever the corridor version, starting a new one from record when there is
+// none. start is when the breach was confirmed. It returns the new infringement, or nil
+// when it was already in progress.
+func (r *infringementRecorder) Record(record *pb.Infringement, position *pb.GeodeticPosition, deviation float64, severity ContainmentSeverity, start, now uint64) *pb.Infringement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := infringementKey(record)
+
+	o, ok := r.open[key]
+	if !ok {
+		record.ID = infringementID(record, start)
+		record.StartTime = start
+		record.Severity = string(severity)
+		o = &openInfringement{record: record, interval: r.interval}
+		r.open[key] = o
+	}
+
+	if deviation > o.record.MaxDeviation {
+		o.record.MaxDeviation = deviation
+	}
+	if severity == ContainmentSeverityCritical {
+		o.record.Severity = string(severity)
+	}
+
+	samples := o.record.Samples
+	if len(samples) == 0 || now-samples[len(samples)-1].Timestamp >= o.interval {
+		o.record.Samples = append(samples, &pb.InfringementSample{
+			Latitude:        float64(position.Latitude),
+			Longitude:       float64(position.Longitude),
+			Altitude:        float64(position.Altitude),
+			Deviation:       deviation,
+			Timestamp:       now,
+			CorridorVersion: record.CorridorVersion,
+		})
+	}
+
+	if len(o.record.Samples) > r.maxSamples {
+		kept := o.record.Samples[:0]
+		for i, s := range o.record.Samples {
+			if i%2 == 0 {
+				kept = append(kept, s)
+			}
+		}
+		o.record.Samples = kept
+		o.interval *= 2
+	}
+
+	if ok {
+		return nil
+	}
+	return record
+}
+
+// Close ends the infringements whose breach is no longer ongoing.
+func (r *infringementRecorder) Close(ongoing func(record *pb.Infringement) bool, now uint64) []*pb.Infringement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ended := []*pb.Infringement{}
+	for key, o := range r.open {
+		if ongoing(o.record) {
+			continue
+		}
+
+		o.record.EndTime = now
+		ended = append(ended, o.record)
+		delete(r.open, key)
+	}
+
+	return ended
+}
+
+// recordInfringement adds the position to the infringement of the drone. A new
+// infringement is stored and its contingency action dispatched.
+func (ms *MainService) recordInfringement(ctx context.Context, record *pb.Infringement, position *pb.GeodeticPosition, deviation float64, severity ContainmentSeverity, action pb.CONTINGENCY_ACTION, start, now uint64) {
+	started := ms.infringements.Record(record, position, deviation, severity, start, now)
+	if started == nil {
+		return
+	}
+
+	config.PrintInfoLog(ctx, "Drone %s infringement started: %s", started.DroneID, started.ID)
+
+	started.CreatedAt = now
+	started.UpdatedAt = now
+	// Nil slices are stored as null, which the $push of the operator actions rejects
+	if started.Timeline == nil {
+		started.Timeline = []*pb.InfringementTimelineEntry{}
+	}
+	if started.Samples == nil {
+		started.Samples = []*pb.InfringementSample{}
+	}
+	_, err := infringementColl.InsertOne(ctx, started)
+	if err != nil {
+		if qmgo.IsDup(err) {
+			config.PrintDebugLog(ctx, "Infringement already stored: %s", started.ID)
+		} else {
+			config.PrintErrorLog(ctx, err, "Failed to insert infringement: %+v", started)
+		}
+	}
+
+	ms.dispatchContingency(ctx, started, action)
+}
+
+// breachOngoing tells whether the breach of the infringement is still confirmed. A drone
+// recovered, or pruned after state_timeout without track, ends it.
+func breachOngoing(containment, geofenceBreaches *containmentStateTracker, record *pb.Infringement) bool {
+	if record.Type == pb.INFRINGEMENT_TYPE_IT_GEOFENCE {
+		return geofenceBreaches.State(geofenceBreachKey(record.DroneID, record.GeofenceID)) == ContainmentStateBreach
+	}
+	return containment.State(record.DroneID) == ContainmentStateBreach
+}
+
+// closeInfringements stores the infringements whose breach ended.
+func (ms *MainService) closeInfringements(ctx context.Context, now uint64) {
+	ongoing := func(record *pb.Infringement) bool {
+		return breachOngoing(ms.containment, ms.geofenceBreaches, record)
+	}
+
+	for _, record := range ms.infringements.Close(ongoing, now) {
+		config.PrintInfoLog(ctx, "Drone %s infringement ended: %s", record.DroneID, record.ID)
+
+		// Leave the operator fields alone, they may have changed since the insert
+		err := infringementColl.UpdateId(ctx, record.ID, bson.M{"$set": bson.M{
+			"end_time":      record.EndTime,
+			"max_deviation": record.MaxDeviation,
+			"severity":      record.Severity,
+			"samples":       record.Samples,
+			"updated_at":    now,
+		}})
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to update infringement by id: %s", record.ID)
+		}
+	}
+}
+
+func (us *MainService) FindInfringementByID(ctx context.Context, id string) (*pb.Infringement, error) {
+	result := &pb.Infringement{}
+	err := infringementColl.Find(ctx, bson.M{"_id": id}).One(result)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to find infringement by id: %s", id)
+
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// InfringementStats aggregates the infringements started between from and to, in
+// millisecond. A zero to means up to now.
+func (us *MainService) InfringementStats(ctx context.Context, from, to uint64) (*InfringementStats, error) {
+	if to == 0 {
+		to = uint64(time.Now().UnixMilli())
+	}
+
+	countBy := func(key interface{}) []bson.M {
+		return []bson.M{
+			{"$group": bson.M{"_id": key, "count": bson.M{"$sum": 1}}},
+			{"$match": bson.M{"_id": bson.M{"$ne": ""}}},
+			{"$sort": bson.M{"_id": 1}},
+		}
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"start_time": bson.M{"$gte": from, "$lte": to}}},
+		{"$facet": bson.M{
+			"by_drone": countBy("$drone_id"),
+			"by_route": countBy(bson.M{"$cond": bson.A{bson.M{"$ne": bson.A{"$corridor_id", ""}}, "$corridor_id", "$order_id"}}),
+			"by_day": countBy(bson.M{"$dateToString": bson.M{
+				"format": "%Y-%m-%d",
+				"date":   bson.M{"$toDate": bson.M{"$toLong": "$start_time"}},
+			}}),
+		}},
+	}
+
+	result := &InfringementStats{}
+	err := infringementColl.Aggregate(ctx, pipeline).One(result)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to aggregate infringements")
+
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	"github.com/qiniu/qmgo"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const infringementRenotifyTag = "infringement-renotify"
+
+var ErrInfringementStatus = errors.New("infringement status does not allow this action")
+
+var ErrNoCauseCode = errors.New("cause code is empty")
+
+var ErrEmptyComment = errors.New("comment is empty")
+
+type InfringementNotification struct {
+	Infringement *pb.Infringement              `json:"infringement"`
+	Action       *pb.InfringementTimelineEntry `json:"action"`    // nil on re-notification
+	Timestamp    uint64                        `json:"timestamp"` // millisecond
+}
+
+var infringementActionEvents = map[pb.INFRINGEMENT_ACTION]NotificationEvent{
+	pb.INFRINGEMENT_ACTION_IA_ACKNOWLEDGE: EventInfringementAcknowledged,
+	pb.INFRINGEMENT_ACTION_IA_RESOLVE:     EventInfringementResolved,
+}
+
+// applyInfringementAction appends the entry to the timeline and sets the fields of the
+// action, provided the infringement is still in one of the given statuses.
+func (us *MainService) applyInfringementAction(ctx context.Context, id string, entry *pb.InfringementTimelineEntry, set bson.M, statuses ...pb.INFRINGEMENT_STATUS) (*pb.Infringement, error) {
+	set["updated_at"] = entry.Timestamp
+	update := bson.M{
+		"$set":  set,
+		"$push": bson.M{"timeline": entry},
+	}
+
+	err := infringementColl.UpdateOne(ctx, bson.M{"_id": id, "status": bson.M{"$in": statuses}}, update)
+	if err != nil {
+		if qmgo.IsErrNoDocuments(err) {
+			_, findErr := us.FindInfringementByID(ctx, id)
+			if findErr != nil {
+				return nil, findErr
+			}
+			err = ErrInfringementStatus
+		}
+		config.PrintErrorLog(ctx, err, "Failed to %s infringement by id: %s", entry.Action, id)
+
+		return nil, err
+	}
+
+	result, err := us.FindInfringementByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if event, ok := infringementActionEvents[entry.Action]; ok {
+		err = us.Notifier().Publish(event, InfringementNotification{
+			Infringement: result,
+			Action:       entry,
+			Timestamp:    entry.Timestamp,
+		})
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to publish %s for infringement: %s", event, id)
+		}
+	}
+
+	return result, nil
+}
+
+// AcknowledgeInfringement lets the user take ownership of an open infringement.
+func (us *MainService) AcknowledgeInfringement(ctx context.Context, id string, user string, comment string) (*pb.Infringement, error) {
+	entry := &pb.InfringementTimelineEntry{
+		Action:    pb.INFRINGEMENT_ACTION_IA_ACKNOWLEDGE,
+		User:      user,
+		Comment:   comment,
+		Timestamp: uint64(time.Now().UnixMilli()),
+	}
+
+	return us.applyInfringementAction(ctx, id, entry, bson.M{
+		"status":          pb.INFRINGEMENT_STATUS_IS_ACKNOWLEDGED,
+		"acknowledged_by": user,
+		"acknowledged_at": entry.Timestamp,
+	}, pb.INFRINGEMENT_STATUS_IS_OPEN)
+}
+
+func (us *MainService) CommentInfringement(ctx context.Context, id string, user string, comment string) (*pb.Infringement, error) {
+	if comment == "" {
+		return nil, ErrEmptyComment
+	}
+
+	entry := &pb.InfringementTimelineEntry{
+		Action:    pb.INFRINGEMENT_ACTION_IA_COMMENT,
+		User:      user,
+		Comment:   comment,
+		Timestamp: uint64(time.Now().UnixMilli()),
+	}
+
+	return us.applyInfringementAction(ctx, id, entry, bson.M{},
+		pb.INFRINGEMENT_STATUS_IS_OPEN, pb.INFRINGEMENT_STATUS_IS_ACKNOWLEDGED, pb.INFRINGEMENT_STATUS_IS_RESOLVED)
+}
+
+// ResolveInfringement closes the infringement with a cause code. An open infringement
+// is acknowledged by the same user on the way.
+func (us *MainService) ResolveInfringement(ctx context.Context, id string, user string, causeCode string, comment string) (*pb.Infringement, error) {
+	if causeCode == "" {
+		return nil, ErrNoCauseCode
+	}
+
+	current, err := us.FindInfringementByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := uint64(time.Now().UnixMilli())
+	set := bson.M{
+		"status":      pb.INFRINGEMENT_STATUS_IS_RESOLVED,
+		"resolved_by": user,
+		"resolved_at": now,
+		"cause_code":  causeCode,
+	}
+	switch current.Status {
+	case pb.INFRINGEMENT_STATUS_IS_OPEN:
+		set["acknowledged_by"] = user
+		set["acknowledged_at"] = now
+	case pb.INFRINGEMENT_STATUS_IS_ACKNOWLEDGED:
+	default:
+		config.PrintErrorLog(ctx, ErrInfringementStatus, "Failed to %s infringement by id: %s", pb.INFRINGEMENT_ACTION_IA_RESOLVE, id)
+
+		return nil, ErrInfringementStatus
+	}
+
+	entry := &pb.InfringementTimelineEntry{
+		Action:    pb.INFRINGEMENT_ACTION_IA_RESOLVE,
+		User:      user,
+		Comment:   comment,
+		CauseCode: causeCode,
+		Timestamp: now,
+	}
+
+	return us.applyInfringementAction(ctx, id, entry, set, current.Status)
+}
+
+// renotifyInfringements publishes again the infringements nobody acknowledged
+// within the re-notify interval.
+func (ms *MainService) renotifyInfringements(ctx context.Context) error {
+	now := uint64(time.Now().UnixMilli())
+	before := now - uint64(ms.SvcConfig.ContainmentConfig.Renotify)
+
+	result := []pb.Infringement{}
+	err := infringementColl.Find(ctx, bson.M{
+		"status":     pb.INFRINGEMENT_STATUS_IS_OPEN,
+		"start_time": bson.M{"$lte": before},
+	}).All(&result)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to find unacknowledged infringements")
+
+		return err
+	}
+
+	for i := range result {
+		err = ms.Notifier().Publish(EventInfringementUnacknowledged, InfringementNotification{
+			Infringement: &result[i],
+			Timestamp:    now,
+		})
+		if err != nil {
+			config.PrintErrorLog(ctx, err, "Failed to publish %s for infringement: %s", EventInfringementUnacknowledged, result[i].ID)
+		}
+	}
+
+	return nil
+}
+
+func (ms *MainService) infringementRenotifyJob() {
+	ctx := log.Logger.WithContext(context.Background())
+
+	ms.renotifyInfringements(ctx)
+}
+
+func (ms *MainService) StartInfringementRenotify(ctx context.Context) error {
+	interval := time.Duration(ms.SvcConfig.ContainmentConfig.Renotify) * time.Millisecond
+	if interval <= 0 {
+		config.PrintInfoLog(ctx, "Infringement re-notification is disabled")
+
+		return nil
+	}
+
+	_, err := ms.scheduler.Every(interval).SingletonMode().Tag(infringementRenotifyTag).Do(ms.infringementRenotifyJob)
+	if err != nil {
+		return err
+	}
+
+	config.PrintInfoLog(ctx, "Re-notify unacknowledged infringements every %v", interval)
+
+	return nil
+}
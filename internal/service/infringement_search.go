@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	util "172.21.5.249/air-trans/at-drone/internal/service/util"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	mongobuilder "go.jtlabs.io/mongo"
+	queryoptions "go.jtlabs.io/query"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var infringementSchemaBuilder = mongobuilder.NewQueryBuilder(
+	INFRINGEMENT,
+	bson.M{
+		"$jsonSchema": bson.M{
+			"bsonType": "object",
+			"properties": bson.M{
+				"_id": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"type": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"drone_id": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"object_track_id": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"order_id": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"corridor_id": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"geofence_id": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"start_time": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"end_time": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"max_deviation": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"severity": bson.M{
+					"bsonType": "string",
+					"required": true,
+				},
+				"samples": bson.M{
+					"bsonType": "Array",
+					"required": true,
+				},
+				"created_at": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+				"updated_at": bson.M{
+					"bsonType": "float",
+					"required": true,
+				},
+			},
+		},
+	},
+	true,
+)
+
+func (us *MainService) SearchInfringement(ctx context.Context, queryOpts queryoptions.Options) (*[]pb.Infringement, int64) {
+	filter, sorts, skip, limit, projection, err := util.ParseQueryOptions(infringementSchemaBuilder, queryOpts)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to parse query option")
+
+		return nil, 0
+	}
+
+	query := infringementColl.Find(ctx, filter)
+
+	count, err := query.Count()
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to count")
+
+		return nil, 0
+	}
+
+	result := []pb.Infringement{}
+	err = query.Skip(skip).Limit(limit).Sort(sorts...).Select(projection).All(&result)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to query")
+
+		return nil, 0
+	}
+
+	return &result, count
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"testing"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+)
+
+// sample is a monitor run, missed when the track did not report.
+type sample struct {
+	inside bool
+	missed bool
+}
+
+func TestInfringementFlickeringGeofence(t *testing.T) {
+	cfg := config.ContainmentConfig{WindowSamples: 3, ConfirmSamples: 2, MaxSamples: 10, SampleInterval: 1000}
+
+	tests := []struct {
+		name     string
+		samples  []sample
+		commands int // contingency commands, one per infringement started
+		closed   int
+	}{
+		{
+			name: "flickering on the edge is one breach",
+			samples: []sample{
+				{inside: true}, {inside: true},
+				{inside: false}, {inside: true}, {inside: true},
+				{inside: false}, {inside: true}, {inside: true},
+				{missed: true},
+				{inside: false}, {inside: true}, {inside: true},
+			},
+			commands: 1,
+			closed:   0,
+		},
+		{
+			name: "recovered then breached again is two breaches",
+			samples: []sample{
+				{inside: true}, {inside: true}, {inside: false}, {inside: false},
+				{inside: true}, {inside: true}, {inside: false}, {inside: false},
+			},
+			commands: 2,
+			closed:   2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			containment := newContainmentStateTracker(cfg)
+			geofenceBreaches := newContainmentStateTracker(cfg)
+			recorder := newInfringementRecorder(cfg)
+			ongoing := func(record *pb.Infringement) bool {
+				return breachOngoing(containment, geofenceBreaches, record)
+			}
+
+			key := geofenceBreachKey("drone", "geofence")
+			commands := map[string]int{}
+			closed := 0
+			for i, s := range tt.samples {
+				now := uint64(i+1) * 1000
+				if !s.missed && geofenceBreaches.Confirm(key, s.inside, now) == ContainmentStateBreach && s.inside {
+					record := &pb.Infringement{Type: pb.INFRINGEMENT_TYPE_IT_GEOFENCE, DroneID: "drone", GeofenceID: "geofence"}
+					started := recorder.Record(record, &pb.GeodeticPosition{}, 1, ContainmentSeverityMajor, geofenceBreaches.Since(key), now)
+					if started != nil {
+						commands[started.ID]++
+					}
+				}
+				closed += len(recorder.Close(ongoing, now))
+			}
+
+			if len(commands) != tt.commands {
+				t.Errorf("commands = %d, want %d", len(commands), tt.commands)
+			}
+			for id, n := range commands {
+				if n != 1 {
+					t.Errorf("command %s sent %d times, want once", id, n)
+				}
+			}
+			if closed != tt.closed {
+				t.Errorf("closed = %d, want %d", closed, tt.closed)
+			}
+		})
+	}
+}
+
+func TestInfringementIDPerBreach(t *testing.T) {
+	record := &pb.Infringement{Type: pb.INFRINGEMENT_TYPE_IT_GEOFENCE, DroneID: "drone", GeofenceID: "geofence"}
+
+	if infringementID(record, 1000) != infringementID(record, 1000) {
+		t.Errorf("infringementID() differs for the same breach")
+	}
+	if infringementID(record, 1000) == infringementID(record, 2000) {
+		t.Errorf("infringementID() is the same for two breaches")
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	geo "172.21.5.249/air-trans/at-drone/internal/geo"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+)
+
+type IntrusionAlert struct {
+	IntruderTrack *pb.ObjectTrack `json:"intruder_track"`
+	Track         *pb.ObjectTrack `json:"track"` // affected drone
+	IntruderID    string          `json:"intruder_id"`
+	DroneID       string          `json:"drone_id"`
+	OrderID       string          `json:"order_id"`
+	CorridorID    string          `json:"corridor_id"`
+	Inside        bool            `json:"inside"`         // intruder already inside the corridor
+	TimeToEntry   float64         `json:"time_to_entry"`  // second, 0 when inside
+	TimeToCPA     float64         `json:"time_to_cpa"`    // second, closest approach to the affected drone within the look-ahead
+	HorizontalCPA float64         `json:"horizontal_cpa"` // meter
+	VerticalCPA   float64         `json:"vertical_cpa"`   // meter
+	Timestamp     uint64          `json:"timestamp"`      // millisecond
+}
+
+// droneRegistry caches the IDs of the registered drones, tracks with another
+// ObjectID are non-cooperative.
+type droneRegistry struct {
+	mu       sync.Mutex
+	ids      map[string]struct{}
+	loadedAt time.Time
+}
+
+func (ms *MainService) registeredDrones(ctx context.Context) (map[string]struct{}, error) {
+	r := ms.drones
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	maxAge := time.Duration(ms.SvcConfig.ContainmentConfig.IndexRefresh) * time.Millisecond
+	if r.ids != nil && time.Since(r.loadedAt) < maxAge {
+		return r.ids, nil
+	}
+
+	drones, err := ms.FindDroneAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.ids = make(map[string]struct{}, len(drones))
+	for i := range drones {
+		r.ids[drones[i].DroneID] = struct{}{}
+	}
+	r.loadedAt = time.Now()
+
+	return r.ids, nil
+}
+
+// predictIntrusion steps the intruder along its velocity and returns the first time, in
+// second, it is inside the corridor of the mission, or false when it stays out over the horizon.
+func (ms *MainService) predictIntrusion(intruder separationTrack, mission *ContainmentResult) (float64, bool) {
+	cfg := ms.SvcConfig.ContainmentConfig
+	geometry := mission.geometry
+
+	if insideCorridor(geometry, intruder.lat, intruder.lon, intruder.alt, cfg.Radius) {
+		return 0, true
+	}
+	if cfg.PredictionStep <= 0 {
+		return 0, false
+	}
+
+	speed := intruder.velocity.Horizontal().Norm()
+	heading := float64(intruder.track.GetPolarVelocity().GetHeading())
+	for t := cfg.PredictionStep; t <= cfg.Horizon; t += cfg.PredictionStep {
+		sec := float64(t) / 1000
+		pLat, pLon := geo.Destination(intruder.lat, intruder.lon, heading, speed*sec)
+		pAlt := intruder.alt + intruder.velocity.Z*sec
+
+		if insideCorridor(geometry, pLat, pLon, pAlt, cfg.Radius) {
+			return sec, true
+		}
+	}
+
+	return 0, false
+}
+
+// CheckIntrusionAll alerts the operators of a mission when a track that is not one of
+// our drones is inside its corridor or predicted to enter it within the look-ahead.
+// missions maps the drones to their containment result of this run.
+func (ms *MainService) CheckIntrusionAll(ctx context.Context, tracks []*pb.ObjectTrack, missions map[string]*ContainmentResult, now uint64) {
+	if len(missions) == 0 {
+		return
+	}
+
+	registered, err := ms.registeredDrones(ctx)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to load registered drones")
+
+		return
+	}
+
+	cfg := ms.SvcConfig.ContainmentConfig
+	horizon := float64(cfg.Horizon) / 1000
+
+	// Corridors built from the order flight route are not indexed
+	byCorridor := map[string][]*ContainmentResult{}
+	unindexed := []*ContainmentResult{}
+	droneTracks := map[string]*pb.ObjectTrack{}
+	for _, track := range tracks {
+		if track == nil {
+			continue
+		}
+		mission, ok := missions[track.ObjectID]
+		if !ok || mission.geometry == nil {
+			continue
+		}
+
+		droneTracks[mission.DroneID] = track
+		if mission.CorridorID == "" {
+			unindexed = append(unindexed, mission)
+		} else {
+			byCorridor[mission.CorridorID] = append(byCorridor[mission.CorridorID], mission)
+		}
+	}
+
+	current := map[string]map[string]struct{}{}
+	payloads := map[string]map[string]*IntrusionAlert{}
+	for _, track := range tracks {
+		if track == nil || track.Position == nil {
+			continue
+		}
+		if _, ok := registered[track.ObjectID]; ok {
+			continue
+		}
+
+		intruder := ms.newSeparationTrack(track)
+		reach := geo.PointBBox(intruder.lat, intruder.lon).Buffer(intruder.velocity.Horizontal().Norm() * horizon)
+
+		candidates := append([]*ContainmentResult{}, unindexed...)
+		for _, id := range ms.volumes.CorridorsNear(reach) {
+			candidates = append(candidates, byCorridor[id]...)
+		}
+
+		for _, mission := range candidates {
+			entry, ok := ms.predictIntrusion(intruder, mission)
+			if !ok {
+				continue
+			}
+
+			droneTrack := droneTracks[mission.DroneID]
+			p, v := relativeMotion(ms.newSeparationTrack(droneTrack), intruder)
+			tCPA, cpa := closestApproach(p, v, horizon)
+
+			alert := &IntrusionAlert{
+				IntruderTrack: track,
+				Track:         droneTrack,
+				IntruderID:    track.ObjectID,
+				DroneID:       mission.DroneID,
+				OrderID:       mission.OrderID,
+				CorridorID:    mission.CorridorID,
+				Inside:        entry == 0,
+				TimeToEntry:   entry,
+				TimeToCPA:     tCPA,
+				HorizontalCPA: cpa.Horizontal().Norm(),
+				VerticalCPA:   math.Abs(cpa.Z),
+				Timestamp:     now,
+			}
+
+			if current[mission.DroneID] == nil {
+				current[mission.DroneID] = map[string]struct{}{}
+				payloads[mission.DroneID] = map[string]*IntrusionAlert{}
+			}
+			current[mission.DroneID][track.ObjectID] = struct{}{}
+			payloads[mission.DroneID][track.ObjectID] = alert
+		}
+	}
+
+	for droneID, intruders := range ms.intrusions.Replace(current) {
+		for intruderID := range intruders {
+			config.PrintDebugLog(ctx, "Non-cooperative track %s intruding the corridor of drone %s", intruderID, droneID)
+
+			err := ms.Notifier().Publish(EventIntrusionDetected, payloads[droneID][intruderID])
+			if err != nil {
+				config.PrintErrorLog(ctx, err, "Failed to publish intrusion for drone: %s", droneID)
+			}
+		}
+	}
+}
@@ -24,6 +24,12 @@ const (
 	// track_history = "track_history"
 	HISTORY_TRACK_PREFIX = "track"
 	OBJECT_TRACK         = "object_track"
+	CORRIDOR             = "corridor"
+	GEOFENCE             = "geofence"
+	FLIGHT_RESTRICTION   = "flight_restriction"
+	INFRINGEMENT         = "infringement"
+	CONTINGENCY_COMMAND  = "contingency_command"
+	CORRIDOR_VERSION     = "corridor_version"
 )
 
 var db *qmgo.Database
@@ -31,25 +37,57 @@ var db *qmgo.Database
 var droneColl *qmgo.Collection
 var trackHistoryColl *qmgo.Collection
 var objectTrackColl *qmgo.Collection
+var corridorColl *qmgo.Collection
+var geofenceColl *qmgo.Collection
+var flightRestrictionColl *qmgo.Collection
+var infringementColl *qmgo.Collection
+var contingencyCommandColl *qmgo.Collection
+var corridorVersionColl *qmgo.Collection
 
 func initColl() {
 	droneColl = db.Collection(DRONE)
 	objectTrackColl = db.Collection(OBJECT_TRACK)
+	corridorColl = db.Collection(CORRIDOR)
+	geofenceColl = db.Collection(GEOFENCE)
+	flightRestrictionColl = db.Collection(FLIGHT_RESTRICTION)
+	infringementColl = db.Collection(INFRINGEMENT)
+	contingencyCommandColl = db.Collection(CONTINGENCY_COMMAND)
+	corridorVersionColl = db.Collection(CORRIDOR_VERSION)
 	// trackHistoryColl = db.Collection(track_history)
 	createIndex(reflect.TypeOf(pb.Drone{}), droneColl)
 	createIndex(reflect.TypeOf(pb.Drone{}), objectTrackColl)
+	createIndex(reflect.TypeOf(pb.ContainmentCorridor{}), corridorColl)
+	createIndex(reflect.TypeOf(pb.Geofence{}), geofenceColl)
+	createIndex(reflect.TypeOf(pb.FlightRestriction{}), flightRestrictionColl)
+	createIndex(reflect.TypeOf(pb.Infringement{}), infringementColl)
+	createIndex(reflect.TypeOf(pb.ContingencyCommand{}), contingencyCommandColl)
+	createIndex(reflect.TypeOf(pb.ContainmentCorridorVersion{}), corridorVersionColl)
 
 	// createIndex(reflect.TypeOf(pb.DroneTrack{}), trackHistoryColl)
 
 }
 
 type MainService struct {
-	DbClient       *qmgo.Client
-	gClient        *gclient.Client
-	SvcConfig      *config.ServiceConfig
-	scheduler      *gocron.Scheduler
-	NATSConnection *nats.Conn
-	notifier       *Notifier
+	DbClient           *qmgo.Client
+	gClient            *gclient.Client
+	SvcConfig          *config.ServiceConfig
+	scheduler          *gocron.Scheduler
+	NATSConnection     *nats.Conn
+	notifier           *Notifier
+	containment        *containmentStateTracker
+	geofenceViolations *alertSet
+	geofenceBreaches   *containmentStateTracker
+	volumes            *volumeIndex
+	corridors          *corridorCache
+	climbRates         *climbRateEstimator
+	activeSegments     *activeSegmentTracker
+	breachPredictions  *alertSet
+	infringements      *infringementRecorder
+	separations        *alertSet
+	intrusions         *alertSet
+	drones             *droneRegistry
+	restrictions       *restrictionRegistry
+	heights            heightModels
 }
 
 func createIndex(rType reflect.Type, collection *qmgo.Collection) {
@@ -100,13 +138,32 @@ func New(dbClient *qmgo.Client, cfg config.ServiceConfig, gc *gclient.Client, nc
 
 	initColl()
 
+	heights := heightModels{
+		geoid:   newGeoidModel(cfg.GeoidConfig),
+		terrain: newTerrainModel(cfg.TerrainConfig),
+	}
+
 	return &MainService{
-		DbClient:       dbClient,
-		gClient:        gc,
-		SvcConfig:      &cfg,
-		scheduler:      gocron.NewScheduler(time.UTC),
-		NATSConnection: nc,
-		notifier:       NewNotifier(),
+		DbClient:           dbClient,
+		gClient:            gc,
+		SvcConfig:          &cfg,
+		scheduler:          gocron.NewScheduler(time.UTC),
+		NATSConnection:     nc,
+		notifier:           NewNotifier(),
+		containment:        newContainmentStateTracker(cfg.ContainmentConfig),
+		geofenceViolations: newAlertSet(),
+		geofenceBreaches:   newContainmentStateTracker(cfg.ContainmentConfig),
+		volumes:            newVolumeIndex(cfg.ContainmentConfig, heights),
+		corridors:          newCorridorCache(),
+		climbRates:         newClimbRateEstimator(),
+		activeSegments:     newActiveSegmentTracker(),
+		breachPredictions:  newAlertSet(),
+		infringements:      newInfringementRecorder(cfg.ContainmentConfig),
+		separations:        newAlertSet(),
+		intrusions:         newAlertSet(),
+		drones:             &droneRegistry{},
+		restrictions:       &restrictionRegistry{},
+		heights:            heights,
 	}
 }
 
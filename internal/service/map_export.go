@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	export "172.21.5.249/air-trans/at-drone/internal/export"
+	geo "172.21.5.249/air-trans/at-drone/internal/geo"
+	util "172.21.5.249/air-trans/at-drone/internal/service/util"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+)
+
+// Vertices of each half circle closing a corridor segment
+const corridorCapVertices = 8
+
+// segmentVolume returns the area within halfWidth of the leg of segment i, a rectangle
+// closed by two half circles, between the lowest floor and highest ceiling of the segment.
+func (g *corridorGeometry) segmentVolume(i int, radius float64) export.Volume {
+	leg := g.legs[i]
+	A, B := leg.from.Horizontal(), leg.to.Horizontal()
+
+	halfWidth, floor, ceiling := segmentLimits(g.corridor, i, radius, leg.from.Z)
+	_, endFloor, endCeiling := segmentLimits(g.corridor, i, radius, leg.to.Z)
+	floor, ceiling = math.Min(floor, endFloor), math.Max(ceiling, endCeiling)
+
+	heading := 0.0
+	if AB := B.Sub(A); AB.Dot(AB) > 0 {
+		heading = math.Atan2(AB.Y, AB.X)
+	}
+
+	ring := make([]export.Position, 0, 2*(corridorCapVertices+1))
+	halfCircle := func(center geo.Vec, from float64) {
+		for k := 0; k <= corridorCapVertices; k++ {
+			angle := from + math.Pi*float64(k)/corridorCapVertices
+			lat, lon, _ := g.plane.ToGeodetic(center.Add(geo.Vec{X: halfWidth * math.Cos(angle), Y: halfWidth * math.Sin(angle)}))
+			ring = append(ring, export.Position{Latitude: lat, Longitude: lon})
+		}
+	}
+	halfCircle(B, heading-math.Pi/2)
+	halfCircle(A, heading+math.Pi/2)
+
+	return export.Volume{Ring: ring, Floor: floor, Ceiling: ceiling}
+}
+
+// turnVolume returns the band within the half-width of segment i around the arc ending
+// the segment, between the limits of the segments on both sides.
+func (g *corridorGeometry) turnVolume(i int, radius float64) export.Volume {
+	t := g.turns[i+1]
+
+	halfWidth, floor, ceiling := segmentLimits(g.corridor, i, radius, t.entry.Z)
+	_, exitFloor, exitCeiling := segmentLimits(g.corridor, i+1, radius, t.exit.Z)
+	floor, ceiling = math.Min(floor, exitFloor), math.Max(ceiling, exitCeiling)
+
+	sweep := t.angle
+	if !t.left {
+		sweep = -sweep
+	}
+
+	ring := make([]export.Position, 0, 2*(corridorCapVertices+1))
+	arc := func(r float64, reverse bool) {
+		for k := 0; k <= corridorCapVertices; k++ {
+			step := k
+			if reverse {
+				step = corridorCapVertices - k
+			}
+			angle := t.start + sweep*float64(step)/corridorCapVertices
+			lat, lon, _ := g.plane.ToGeodetic(t.center.Add(geo.Vec{X: r * math.Cos(angle), Y: r * math.Sin(angle)}))
+			ring = append(ring, export.Position{Latitude: lat, Longitude: lon})
+		}
+	}
+	arc(t.radius+halfWidth, false)
+	arc(math.Max(t.radius-halfWidth, 0), true)
+
+	return export.Volume{Ring: ring, Floor: floor, Ceiling: ceiling}
+}
+
+// corridorFeature exports the volumes of the corridor. WGS84 limits are converted to
+// AMSL, AGL ones are left as they are.
+func corridorFeature(corridor *pb.ContainmentCorridor, radius float64, perf turnPerformance, heights heightModels) (export.Feature, error) {
+	geometry, err := newCorridorGeometry(corridor, perf, heights)
+	if err != nil {
+		return export.Feature{}, err
+	}
+
+	reference := corridor.AltitudeReference
+	if reference == pb.ALTITUDE_REFERENCE_AR_WGS84 {
+		reference = pb.ALTITUDE_REFERENCE_AR_AMSL
+	}
+
+	feature := export.Feature{
+		Name: corridor.Name,
+		Properties: map[string]interface{}{
+			"id":                 corridor.ID,
+			"order_id":           corridor.OrderID,
+			"drone_id":           corridor.DroneID,
+			"version":            corridor.Version,
+			"altitude_reference": reference.String(),
+		},
+	}
+	for i := range geometry.legs {
+		feature.Volumes = append(feature.Volumes, geometry.segmentVolume(i, radius))
+		if geometry.turns[i+1] != nil {
+			feature.Volumes = append(feature.Volumes, geometry.turnVolume(i, radius))
+		}
+	}
+
+	if corridor.AltitudeReference == pb.ALTITUDE_REFERENCE_AR_WGS84 {
+		for i, v := range feature.Volumes {
+			n := heights.geoid.Undulation(v.Ring[0].Latitude, v.Ring[0].Longitude)
+			feature.Volumes[i].Floor -= n
+			feature.Volumes[i].Ceiling -= n
+		}
+	}
+
+	return feature, nil
+}
+
+func (ms *MainService) ExportCorridor(ctx context.Context, id string) (*export.Document, error) {
+	corridor, err := ms.FindCorridorByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	feature, err := corridorFeature(corridor, ms.SvcConfig.ContainmentConfig.Radius, newTurnPerformance(ms.SvcConfig.ContainmentConfig), ms.heights)
+	if err != nil {
+		return nil, err
+	}
+
+	return &export.Document{Name: corridor.Name, Features: []export.Feature{feature}}, nil
+}
+
+func geofenceFeature(g *pb.Geofence) export.Feature {
+	ring := make([]export.Position, 0, len(g.GetVertices()))
+	for _, v := range g.GetVertices() {
+		ring = append(ring, export.Position{Latitude: v.Latitude, Longitude: v.Longitude})
+	}
+
+	return export.Feature{
+		Name: g.Name,
+		Properties: map[string]interface{}{
+			"id":       g.ID,
+			"type":     g.Type.String(),
+			"category": g.Category,
+			"active":   g.Active,
+		},
+		Volumes: []export.Volume{{Ring: ring, Floor: g.MinAltitude, Ceiling: g.MaxAltitude}},
+	}
+}
+
+// ExportGeofences exports the geofence with the id, or all the active ones when id is empty.
+func (ms *MainService) ExportGeofences(ctx context.Context, id string) (*export.Document, error) {
+	if id != "" {
+		g, err := ms.FindGeofenceByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		return &export.Document{Name: g.Name, Features: []export.Feature{geofenceFeature(g)}}, nil
+	}
+
+	geofences, err := ms.FindActiveGeofences(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &export.Document{Name: "geofences", Features: []export.Feature{}}
+	for i := range geofences {
+		doc.Features = append(doc.Features, geofenceFeature(&geofences[i]))
+	}
+
+	return doc, nil
+}
+
+// ExportTrack exports the positions stored in the track history of a drone in [from, to],
+// by default the last hour.
+func (ms *MainService) ExportTrack(ctx context.Context, droneID string, from, to uint64) (*export.Document, error) {
+	if to == 0 {
+		to = uint64(time.Now().UnixMilli())
+	}
+	if from == 0 && to > uint64(time.Hour/time.Millisecond) {
+		from = to - uint64(time.Hour/time.Millisecond)
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid time range %d - %d", from, to)
+	}
+
+	histories, err := ms.FindTrackHistoryRange(ctx, droneID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	feature := export.Feature{
+		Name: droneID,
+		Properties: map[string]interface{}{
+			"drone_id": droneID,
+			"from":     from,
+			"to":       to,
+		},
+	}
+	for _, h := range util.ConvertToJSONResponse(ctx, histories) {
+		position := h.LocationJson.GetGeodeticPosition()
+		if position == nil {
+			continue
+		}
+
+		feature.Track = append(feature.Track, export.Position{
+			Latitude:  float64(position.Latitude),
+			Longitude: float64(position.Longitude),
+			Altitude:  ms.historyAltitude(position).MSL,
+			Timestamp: h.TrackHistory.CreatedAt,
+		})
+	}
+
+	return &export.Document{Name: droneID, Features: []export.Feature{feature}}, nil
+}
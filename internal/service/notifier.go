@@ -9,6 +9,18 @@ type NotificationEvent string
 
 const (
 	EventFlightContainmentInfringement NotificationEvent = "flight_containment.infringed"
+	EventFlightContainmentWarning      NotificationEvent = "flight_containment.warning"
+	EventFlightContainmentRecovered    NotificationEvent = "flight_containment.recovered"
+	EventFlightContainmentPredicted    NotificationEvent = "flight_containment.predicted_breach"
+	EventGeofenceViolated              NotificationEvent = "geofence.violated"
+	EventFlightRestrictionActivated    NotificationEvent = "flight_restriction.activated"
+	EventFlightRestrictionDeactivated  NotificationEvent = "flight_restriction.deactivated"
+	EventInfringementAcknowledged      NotificationEvent = "infringement.acknowledged"
+	EventInfringementResolved          NotificationEvent = "infringement.resolved"
+	EventInfringementUnacknowledged    NotificationEvent = "infringement.unacknowledged"
+	EventSeparationConflict            NotificationEvent = "separation.conflict"
+	EventIntrusionDetected             NotificationEvent = "intrusion.detected"
+	EventCorridorAmended               NotificationEvent = "corridor.amended"
 )
 
 type eventMessage struct {
@@ -31,22 +43,26 @@ func NewNotifier() *Notifier {
 	}
 }
 
-func (n *Notifier) Subscribe(event NotificationEvent) (<-chan []byte, func()) {
+func (n *Notifier) Subscribe(events ...NotificationEvent) (<-chan []byte, func()) {
 	sub := &subscriber{ch: make(chan []byte, 16)}
 	n.mu.Lock()
-	if n.subscribers[event] == nil {
-		n.subscribers[event] = make(map[*subscriber]struct{})
+	for _, event := range events {
+		if n.subscribers[event] == nil {
+			n.subscribers[event] = make(map[*subscriber]struct{})
+		}
+		n.subscribers[event][sub] = struct{}{}
 	}
-	n.subscribers[event][sub] = struct{}{}
 	n.mu.Unlock()
 	return sub.ch, func() {
 		n.mu.Lock()
-		subs := n.subscribers[event]
-		if subs != nil {
-			if _, ok := subs[sub]; ok {
-				delete(subs, sub)
-				if len(subs) == 0 {
-					delete(n.subscribers, event)
+		for _, event := range events {
+			subs := n.subscribers[event]
+			if subs != nil {
+				if _, ok := subs[sub]; ok {
+					delete(subs, sub)
+					if len(subs) == 0 {
+						delete(n.subscribers, event)
+					}
 				}
 			}
 		}
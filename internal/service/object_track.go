@@ -175,27 +175,13 @@ func (ms *MainService) FindObjectTrackAll(ctx context.Context) ([]pb.ObjectTrack
 	return rs, err
 }
 
-func (ms *MainService) CheckFlightContainmentAll(ctx context.Context) error {
-	inMemObjectTracks, err := ms.FindAllInMemObjectTrack(ctx, &emptypb.Empty{})
-	if err != nil {
-		config.PrintErrorLog(ctx, err, "Failed to get all in_mem object tracks")
-		return err
-	}
-	//Continously check flight containment, if find an infringment, .e.g. CheckFlightContainment return true, then Publish the track to websocket with event EventFlightContainmentInfringement NotificationEvent = "flight_containment.infringed"
-	for _, v := range inMemObjectTracks {
-		ms.CheckFlightContainment(float64(v.Position.Latitude), float64(v.Position.Longitude), float64(v.Position.Altitude))
-		//...
-		ms.Notifier().Publish()
-	}
-
-	return err
-}
-
 type MobileDroneResponse struct {
 	ObjectID      string              `json:"drone_id"`
 	PolarVelocity pb.PolarVelocity    `json:"polar_velocity"`
 	Position      pb.GeodeticPosition `json:"position"`
+	Altitude      TrackAltitude       `json:"altitude"` // of the position in both vertical datums
 	UpdatedAt     uint64              `json:"updated_at"`
+	Progress      *RouteProgress      `json:"progress,omitempty"`
 }
 
 func (ms *MainService) FindObjectTrackByDroneID(ctx context.Context, id string) (*MobileDroneResponse, error) {
@@ -211,7 +197,12 @@ func (ms *MainService) FindObjectTrackByDroneID(ctx context.Context, id string)
 			rs.ObjectID = v.ObjectID
 			rs.PolarVelocity = *v.PolarVelocity
 			rs.Position = *v.Position
+			rs.Altitude = ms.trackAltitude(v)
 			rs.UpdatedAt = v.UpdatedAt
+
+			if progress, err := ms.TrackRouteProgress(ctx, v); err == nil {
+				rs.Progress = progress
+			}
 		}
 	}
 	return &rs, err
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	route "172.21.5.249/air-trans/at-drone/internal/route"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+)
+
+type RouteImportOptions struct {
+	FileName     string
+	Format       route.Format            // detected from the file when empty
+	Reference    route.AltitudeReference // for the altitudes the format leaves unqualified
+	HomeAltitude *float64                // meter AMSL, overrides the home position of the file
+}
+
+// parseRoute returns the waypoints of an uploaded route with AMSL altitudes. Terrain
+// referenced items without terrain data under them are reported as issues.
+func (ms *MainService) parseRoute(ctx context.Context, data []byte, opt RouteImportOptions) ([]route.Waypoint, error) {
+	format := opt.Format
+	if format == "" {
+		detected, err := route.DetectFormat(opt.FileName, data)
+		if err != nil {
+			return nil, err
+		}
+		format = detected
+	}
+
+	plan, err := route.Parse(data, format, opt.Reference)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to parse %s route: %s", format, opt.FileName)
+
+		return nil, err
+	}
+
+	home := opt.HomeAltitude
+	if home == nil && plan.Home != nil {
+		home = &plan.Home.Altitude
+	}
+	waypoints, err := plan.Resolve(home, ms.heights.terrain.Elevation)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to resolve altitudes of %s route: %s", format, opt.FileName)
+
+		return nil, err
+	}
+
+	config.PrintDebugLog(ctx, "Parsed %s route %s: %d waypoints", format, opt.FileName, len(waypoints))
+
+	return waypoints, nil
+}
+
+// ImportCorridor creates the corridor with the waypoints of an uploaded route, its
+// altitudes referenced to the mean sea level.
+func (ms *MainService) ImportCorridor(ctx context.Context, model *pb.ContainmentCorridor, data []byte, opt RouteImportOptions) (*pb.ContainmentCorridor, error) {
+	waypoints, err := ms.parseRoute(ctx, data, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	model.Waypoints = make([]*pb.CorridorWaypoint, 0, len(waypoints))
+	for _, w := range waypoints {
+		model.Waypoints = append(model.Waypoints, &pb.CorridorWaypoint{
+			Latitude:  w.Latitude,
+			Longitude: w.Longitude,
+			Altitude:  w.Altitude,
+		})
+	}
+	model.AltitudeReference = pb.ALTITUDE_REFERENCE_AR_AMSL
+
+	return ms.CreateCorridor(ctx, model, false)
+}
+
+// ImportOrderRoute replaces the flight route of an order with an uploaded route.
+func (ms *MainService) ImportOrderRoute(ctx context.Context, id string, data []byte, opt RouteImportOptions) (*pb.Order, error) {
+	waypoints, err := ms.parseRoute(ctx, data, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := ms.FindOrderByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := make([]*pb.GeodeticPosition, 0, len(waypoints))
+	for _, w := range waypoints {
+		positions = append(positions, &pb.GeodeticPosition{
+			Latitude:  float32(w.Latitude),
+			Longitude: float32(w.Longitude),
+			Altitude:  float32(w.Altitude),
+		})
+	}
+	order.FlightRoute = &pb.FlightRoute{Waypoints: positions}
+
+	return ms.UpdateOrderByID(ctx, order, id)
+}
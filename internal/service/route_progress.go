@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type RouteProgress struct {
+	DroneID           string  `json:"drone_id"`
+	OrderID           string  `json:"order_id"`
+	CorridorID        string  `json:"corridor_id"`
+	ActiveSegment     int     `json:"active_segment"`     // index of the first waypoint of the active segment
+	DistanceFlown     float64 `json:"distance_flown"`     // meter along the route
+	DistanceRemaining float64 `json:"distance_remaining"` // meter along the route
+	RouteLength       float64 `json:"route_length"`       // meter
+	PercentComplete   float64 `json:"percent_complete"`
+	GroundSpeed       float64 `json:"ground_speed"`   // m/s
+	TimeRemaining     float64 `json:"time_remaining"` // second, 0 when the drone does not move
+	ETA               uint64  `json:"eta"`            // millisecond, 0 when the drone does not move
+}
+
+type activeSegment struct {
+	corridorID string
+	version    uint32
+	segment    int
+	along      float64 // meter
+	lastSeen   uint64
+}
+
+// activeSegmentTracker remembers the segment each drone is flying so that progress moves
+// forward along the route rather than to whichever leg is the nearest.
+type activeSegmentTracker struct {
+	mu     sync.Mutex
+	drones map[string]*activeSegment
+}
+
+func newActiveSegmentTracker() *activeSegmentTracker {
+	return &activeSegmentTracker{
+		drones: map[string]*activeSegment{},
+	}
+}
+
+// Update returns the segment and along-track distance of the drone on the corridor of the
+// result, searching from its previous segment on the same corridor version.
+func (t *activeSegmentTracker) Update(result *ContainmentResult, radius float64, now uint64) (int, float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	segment, along := result.NearestSegment, result.AlongTrack
+
+	a, ok := t.drones[result.DroneID]
+	if ok && a.corridorID == result.CorridorID && a.version == result.CorridorVersion && result.geometry != nil {
+		lat, lon := float64(result.Position.Latitude), float64(result.Position.Longitude)
+
+		dev, err := result.geometry.progressAt(lat, lon, result.Altitude.Ellipsoid, radius, a.segment, a.along)
+		if err == nil {
+			segment, along = dev.segment, dev.alongTrack
+		}
+	}
+
+	t.drones[result.DroneID] = &activeSegment{
+		corridorID: result.CorridorID,
+		version:    result.CorridorVersion,
+		segment:    segment,
+		along:      along,
+		lastSeen:   now,
+	}
+
+	return segment, along
+}
+
+func (t *activeSegmentTracker) Prune(before uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, a := range t.drones {
+		if a.lastSeen < before {
+			delete(t.drones, id)
+		}
+	}
+}
+
+func (ms *MainService) TrackRouteProgress(ctx context.Context, track *pb.ObjectTrack) (*RouteProgress, error) {
+	result, err := ms.CheckFlightContainment(ctx, track)
+	if err != nil {
+		return nil, err
+	}
+
+	segment, along := ms.activeSegments.Update(result, ms.SvcConfig.ContainmentConfig.Radius, uint64(time.Now().UnixMilli()))
+	flown := math.Min(along, result.RouteLength)
+	speed := float64(track.GetPolarVelocity().GetSpeed())
+
+	progress := &RouteProgress{
+		DroneID:           result.DroneID,
+		OrderID:           result.OrderID,
+		CorridorID:        result.CorridorID,
+		ActiveSegment:     segment,
+		DistanceFlown:     flown,
+		DistanceRemaining: result.RouteLength - flown,
+		RouteLength:       result.RouteLength,
+		GroundSpeed:       speed,
+	}
+
+	if result.RouteLength > 0 {
+		progress.PercentComplete = 100 * flown / result.RouteLength
+	}
+
+	if speed > 0 {
+		progress.TimeRemaining = progress.DistanceRemaining / speed
+		progress.ETA = uint64(time.Now().UnixMilli()) + uint64(progress.TimeRemaining*1000)
+	}
+
+	return progress, nil
+}
+
+func (ms *MainService) FindRouteProgressByDroneID(ctx context.Context, droneID string) (*RouteProgress, error) {
+	inMemObjectTracks, err := ms.FindAllInMemObjectTrack(ctx, &emptypb.Empty{})
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to get all in_mem object tracks")
+		return nil, err
+	}
+
+	for _, v := range inMemObjectTracks {
+		if v.ObjectID == droneID {
+			return ms.TrackRouteProgress(ctx, v)
+		}
+	}
+
+	return nil, fmt.Errorf("no object track for drone %s", droneID)
+}
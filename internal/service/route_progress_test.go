@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+)
+
+func TestProgressAtOutAndBack(t *testing.T) {
+	// Out to the north and back about 10 m east of the outbound leg
+	corridor := &pb.ContainmentCorridor{
+		AltitudeReference: pb.ALTITUDE_REFERENCE_AR_WGS84,
+		LateralTolerance:  30,
+		VerticalTolerance: 30,
+		Waypoints: []*pb.CorridorWaypoint{
+			{Latitude: 21, Longitude: 105, Altitude: 100},
+			{Latitude: 21.01, Longitude: 105, Altitude: 100},
+			{Latitude: 21, Longitude: 105.0001, Altitude: 100},
+		},
+	}
+	g, err := newCorridorGeometry(corridor, turnPerformance{}, heightModels{})
+	if err != nil {
+		t.Fatalf("newCorridorGeometry() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		active   int
+		along    float64
+		want     int
+	}{
+		{name: "outbound stays outbound", lat: 21.005, lon: 105.00003, active: 0, along: 500, want: 0},
+		{name: "inbound stays inbound", lat: 21.005, lon: 105.00007, active: 1, along: 1600, want: 1},
+		{name: "inbound does not go back to outbound", lat: 21.005, lon: 105.00003, active: 1, along: 1600, want: 1},
+		{name: "outside falls back to the nearest leg", lat: 21.005, lon: 104.9997, active: 1, along: 1600, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dev, err := g.progressAt(tt.lat, tt.lon, 100, 30, tt.active, tt.along)
+			if err != nil {
+				t.Fatalf("progressAt() error = %v", err)
+			}
+
+			if dev.segment != tt.want {
+				t.Errorf("segment = %d, want %d (along-track %.1f m)", dev.segment, tt.want, dev.alongTrack)
+			}
+		})
+	}
+}
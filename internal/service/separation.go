@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"math"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	geo "172.21.5.249/air-trans/at-drone/internal/geo"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+)
+
+type SeparationConflict struct {
+	Track          *pb.ObjectTrack `json:"track"`
+	IntruderTrack  *pb.ObjectTrack `json:"intruder_track"`
+	DroneID        string          `json:"drone_id"`
+	IntruderID     string          `json:"intruder_id"`
+	TimeToConflict float64         `json:"time_to_conflict"` // second, 0 when the separation is already lost
+	TimeToCPA      float64         `json:"time_to_cpa"`      // second, within the look-ahead
+	HorizontalCPA  float64         `json:"horizontal_cpa"`   // meter, horizontal distance at the closest point of approach
+	VerticalCPA    float64         `json:"vertical_cpa"`     // meter, vertical distance at the closest point of approach
+	Timestamp      uint64          `json:"timestamp"`        // millisecond
+}
+
+// timeInterval is a span of time in second, empty when From > To.
+type timeInterval struct {
+	From, To float64
+}
+
+func (i timeInterval) intersect(o timeInterval) timeInterval {
+	return timeInterval{math.Max(i.From, o.From), math.Min(i.To, o.To)}
+}
+
+func (i timeInterval) empty() bool {
+	return i.From > i.To
+}
+
+// horizontalInterval returns when |p + v*t| < limit for a relative position p and velocity v.
+func horizontalInterval(p, v geo.Vec, limit float64) timeInterval {
+	a := v.Dot(v)
+	c := p.Dot(p) - limit*limit
+	if a == 0 {
+		if c < 0 {
+			return timeInterval{math.Inf(-1), math.Inf(1)}
+		}
+		return timeInterval{1, 0}
+	}
+
+	b := 2 * p.Dot(v)
+	disc := b*b - 4*a*c
+	if disc < 0 {
+		return timeInterval{1, 0}
+	}
+
+	sq := math.Sqrt(disc)
+	return timeInterval{(-b - sq) / (2 * a), (-b + sq) / (2 * a)}
+}
+
+// verticalInterval returns when |z + vz*t| < limit.
+func verticalInterval(z, vz, limit float64) timeInterval {
+	if vz == 0 {
+		if math.Abs(z) < limit {
+			return timeInterval{math.Inf(-1), math.Inf(1)}
+		}
+		return timeInterval{1, 0}
+	}
+
+	t1, t2 := (-limit-z)/vz, (limit-z)/vz
+	return timeInterval{math.Min(t1, t2), math.Max(t1, t2)}
+}
+
+// separationTrack is a track with its velocity in east, north and up meters per second.
+type separationTrack struct {
+	track    *pb.ObjectTrack
+	lat, lon float64
+	alt      float64 // meter above the WGS84 ellipsoid
+	velocity geo.Vec
+}
+
+func (ms *MainService) newSeparationTrack(track *pb.ObjectTrack) separationTrack {
+	speed := float64(track.GetPolarVelocity().GetSpeed())
+	heading := float64(track.GetPolarVelocity().GetHeading())
+
+	return separationTrack{
+		track: track,
+		lat:   float64(track.Position.Latitude),
+		lon:   float64(track.Position.Longitude),
+		alt:   ms.trackAltitude(track).Ellipsoid,
+		velocity: geo.Vec{
+			X: speed * math.Sin(heading),
+			Y: speed * math.Cos(heading),
+			Z: ms.climbRates.Rate(track.ObjectID),
+		},
+	}
+}
+
+// closestApproach returns the time within [0, horizon] at which the relative position
+// p moving at v is the closest horizontally, and the relative position then.
+func closestApproach(p, v geo.Vec, horizon float64) (float64, geo.Vec) {
+	t := 0.0
+	if vh := v.Horizontal(); vh.Dot(vh) > 0 {
+		t = math.Max(0, math.Min(horizon, -p.Horizontal().Dot(vh)/vh.Dot(vh)))
+	}
+
+	return t, p.Add(v.Scale(t))
+}
+
+// relativeMotion returns the position and velocity of b in the tangent plane of a.
+func relativeMotion(a, b separationTrack) (geo.Vec, geo.Vec) {
+	plane := geo.NewLocalTangentPlane(a.lat, a.lon, a.alt)
+
+	return plane.ToENU(b.lat, b.lon, b.alt), b.velocity.Sub(a.velocity)
+}
+
+// predictConflict extrapolates both tracks at constant velocity in the tangent plane of a
+// and returns the conflict when both minima are infringed at the same time within horizon seconds.
+func predictConflict(a, b separationTrack, horizontal, vertical, horizon float64) *SeparationConflict {
+	p, v := relativeMotion(a, b)
+
+	loss := horizontalInterval(p.Horizontal(), v.Horizontal(), horizontal).
+		intersect(verticalInterval(p.Z, v.Z, vertical)).
+		intersect(timeInterval{0, horizon})
+	if loss.empty() {
+		return nil
+	}
+
+	tCPA, cpa := closestApproach(p, v, horizon)
+
+	return &SeparationConflict{
+		Track:          a.track,
+		IntruderTrack:  b.track,
+		DroneID:        a.track.ObjectID,
+		IntruderID:     b.track.ObjectID,
+		TimeToConflict: loss.From,
+		TimeToCPA:      tCPA,
+		HorizontalCPA:  cpa.Horizontal().Norm(),
+		VerticalCPA:    math.Abs(cpa.Z),
+	}
+}
+
+// CheckSeparationAll looks for pairs of tracks, at least one of them a registered drone,
+// predicted to lose separation within the look-ahead. Each track is put in a grid with the area it can reach, so only the
+// tracks whose areas overlap are compared.
+func (ms *MainService) CheckSeparationAll(ctx context.Context, tracks []*pb.ObjectTrack, now uint64) {
+	cfg := ms.SvcConfig.ContainmentConfig
+	if cfg.SeparationHorizontal <= 0 {
+		return
+	}
+	horizon := float64(cfg.SeparationHorizon) / 1000
+
+	// Pairs of non-cooperative tracks are nobody's separation to keep
+	registered, err := ms.registeredDrones(ctx)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to load registered drones")
+
+		return
+	}
+
+	cellSize := cfg.IndexCellSize
+	if cellSize <= 0 {
+		cellSize = defaultGridCellSize
+	}
+	grid := geo.NewGridIndex(cellSize)
+
+	candidates := map[string]separationTrack{}
+	boxes := map[string]geo.BBox{}
+	for _, track := range tracks {
+		if track == nil || track.Position == nil || track.ObjectID == "" {
+			continue
+		}
+
+		s := ms.newSeparationTrack(track)
+		reach := s.velocity.Horizontal().Norm()*horizon + cfg.SeparationHorizontal/2
+
+		candidates[track.ObjectID] = s
+		boxes[track.ObjectID] = geo.PointBBox(s.lat, s.lon).Buffer(reach)
+		grid.Insert(track.ObjectID, boxes[track.ObjectID])
+	}
+
+	current := map[string]map[string]struct{}{}
+	payloads := map[string]map[string]*SeparationConflict{}
+	for id := range candidates {
+		for _, other := range grid.Query(boxes[id]) {
+			// Compare each pair once
+			if other <= id {
+				continue
+			}
+			_, registeredA := registered[id]
+			_, registeredB := registered[other]
+			if !registeredA && !registeredB {
+				continue
+			}
+
+			// The registered drone keeps separation from the other track
+			droneID, intruderID := id, other
+			if !registeredA {
+				droneID, intruderID = other, id
+			}
+
+			conflict := predictConflict(candidates[droneID], candidates[intruderID], cfg.SeparationHorizontal, cfg.SeparationVertical, horizon)
+			if conflict == nil {
+				continue
+			}
+			conflict.Timestamp = now
+
+			if current[droneID] == nil {
+				current[droneID] = map[string]struct{}{}
+				payloads[droneID] = map[string]*SeparationConflict{}
+			}
+			current[droneID][intruderID] = struct{}{}
+			payloads[droneID][intruderID] = conflict
+		}
+	}
+
+	for droneID, intruders := range ms.separations.Replace(current) {
+		for intruderID := range intruders {
+			conflict := payloads[droneID][intruderID]
+
+			config.PrintDebugLog(ctx, "Drones %s and %s predicted to lose separation in %.1f s", droneID, intruderID, conflict.TimeToConflict)
+
+			err := ms.Notifier().Publish(EventSeparationConflict, conflict)
+			if err != nil {
+				config.PrintErrorLog(ctx, err, "Failed to publish separation conflict for drones: %s - %s", droneID, intruderID)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	geoid "172.21.5.249/air-trans/at-drone/internal/geoid"
+	terrain "172.21.5.249/air-trans/at-drone/internal/terrain"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	"github.com/rs/zerolog/log"
+)
+
+// heightModels relate the ellipsoid, the mean sea level and the ground.
+type heightModels struct {
+	geoid   *geoid.Model
+	terrain *terrain.Model
+}
+
+// altitudeFrame converts the WGS84 ellipsoid heights of the tracks to the altitude
+// reference of a corridor.
+type altitudeFrame struct {
+	reference pb.ALTITUDE_REFERENCE
+	heights   heightModels
+}
+
+func (f altitudeFrame) altitude(lat, lon, alt float64) (float64, error) {
+	switch f.reference {
+	case pb.ALTITUDE_REFERENCE_AR_AMSL:
+		return f.heights.geoid.MSLHeight(lat, lon, alt), nil
+	case pb.ALTITUDE_REFERENCE_AR_AGL:
+		ground, err := f.heights.terrain.Elevation(lat, lon)
+		if err != nil {
+			return 0, err
+		}
+
+		return f.heights.geoid.MSLHeight(lat, lon, alt) - ground, nil
+	}
+
+	return alt, nil
+}
+
+// msl converts an altitude in the reference of the corridor to the mean sea level.
+func (f altitudeFrame) msl(lat, lon, alt float64) (float64, error) {
+	switch f.reference {
+	case pb.ALTITUDE_REFERENCE_AR_WGS84:
+		return f.heights.geoid.MSLHeight(lat, lon, alt), nil
+	case pb.ALTITUDE_REFERENCE_AR_AGL:
+		ground, err := f.heights.terrain.Elevation(lat, lon)
+		if err != nil {
+			return 0, err
+		}
+
+		return alt + ground, nil
+	}
+
+	return alt, nil
+}
+
+func newTerrainModel(cfg config.TerrainConfig) *terrain.Model {
+	ctx := log.Logger.WithContext(context.Background())
+
+	model, err := terrain.New(cfg.Dir, cfg.CacheTiles)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to load terrain tiles from: %s", cfg.Dir)
+
+		model, _ = terrain.New("", cfg.CacheTiles)
+	}
+
+	if cfg.Dir != "" {
+		config.PrintInfoLog(ctx, "Loaded %d terrain tiles from: %s", model.Tiles(), cfg.Dir)
+	}
+
+	return model
+}
+
+type TerrainClearance struct {
+	Elevation float64 `json:"elevation"`  // meter, ground under the track
+	HeightAGL float64 `json:"height_agl"` // meter above the ground
+	Margin    float64 `json:"margin"`     // meter above the minimum terrain clearance, negative below
+}
+
+// terrainClearance returns the height of a track above the ground from its height above
+// the mean sea level, nil without terrain data at its position.
+func (ms *MainService) terrainClearance(lat, lon, msl float64) *TerrainClearance {
+	elevation, err := ms.heights.terrain.Elevation(lat, lon)
+	if err != nil {
+		return nil
+	}
+
+	return &TerrainClearance{
+		Elevation: elevation,
+		HeightAGL: msl - elevation,
+		Margin:    msl - elevation - ms.SvcConfig.ContainmentConfig.MinTerrainClearance,
+	}
+}
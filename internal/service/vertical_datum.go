@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	geoid "172.21.5.249/air-trans/at-drone/internal/geoid"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	"github.com/rs/zerolog/log"
+)
+
+// parseVerticalDatum reads the datum names of the configuration, AMSL unless WGS84.
+func parseVerticalDatum(s string) pb.ALTITUDE_REFERENCE {
+	switch strings.TrimPrefix(strings.ToUpper(s), "AR_") {
+	case "WGS84", "ELLIPSOID", "HAE":
+		return pb.ALTITUDE_REFERENCE_AR_WGS84
+	}
+
+	return pb.ALTITUDE_REFERENCE_AR_AMSL
+}
+
+func newGeoidModel(cfg config.GeoidConfig) *geoid.Model {
+	ctx := log.Logger.WithContext(context.Background())
+
+	if cfg.File == "" {
+		config.PrintInfoLog(ctx, "No geoid model, AMSL altitudes are taken as WGS84 ones")
+
+		return nil
+	}
+
+	model, err := geoid.Load(cfg.File)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to load geoid model, AMSL altitudes are taken as WGS84 ones")
+
+		return nil
+	}
+
+	config.PrintInfoLog(ctx, "Loaded geoid model %s", model.Name())
+
+	return model
+}
+
+// TrackAltitude is the altitude of a position in both vertical datums.
+type TrackAltitude struct {
+	Datum      pb.ALTITUDE_REFERENCE `json:"datum"`      // of the altitude reported by the position
+	Ellipsoid  float64               `json:"ellipsoid"`  // meter above the WGS84 ellipsoid
+	MSL        float64               `json:"msl"`        // meter above the mean sea level
+	Undulation float64               `json:"undulation"` // meter, geoid above the ellipsoid
+}
+
+// positionAltitude converts an altitude reported in the datum to both datums.
+func (ms *MainService) positionAltitude(lat, lon, alt float64, datum pb.ALTITUDE_REFERENCE) TrackAltitude {
+	n := ms.heights.geoid.Undulation(lat, lon)
+	if datum == pb.ALTITUDE_REFERENCE_AR_WGS84 {
+		return TrackAltitude{Datum: datum, Ellipsoid: alt, MSL: alt - n, Undulation: n}
+	}
+
+	return TrackAltitude{Datum: pb.ALTITUDE_REFERENCE_AR_AMSL, Ellipsoid: alt + n, MSL: alt, Undulation: n}
+}
+
+// sourceTypeName returns the configuration key of the source type of a source track:
+// radar, ew, cam or unknown.
+func sourceTypeName(source *pb.TrackMessage) string {
+	return strings.ToLower(strings.TrimPrefix(source.GetSourceInfo().GetSourceType().String(), "SOURCE_TYPE_"))
+}
+
+// trackDatum returns the vertical datum of the altitude of a track, the one configured
+// for the source of its first source track or by default for all tracks.
+func (ms *MainService) trackDatum(track *pb.ObjectTrack) pb.ALTITUDE_REFERENCE {
+	cfg := ms.SvcConfig.GeoidConfig
+	if sources := track.GetSourceTracks(); len(sources) > 0 && sources[0] != nil {
+		if datum, ok := cfg.SourceDatums[sourceTypeName(sources[0])]; ok {
+			return parseVerticalDatum(datum)
+		}
+	}
+
+	return parseVerticalDatum(cfg.TrackDatum)
+}
+
+// trackAltitude converts the altitude of the track position from the datum of its source.
+func (ms *MainService) trackAltitude(track *pb.ObjectTrack) TrackAltitude {
+	p := track.Position
+
+	return ms.positionAltitude(float64(p.Latitude), float64(p.Longitude), float64(p.Altitude), ms.trackDatum(track))
+}
+
+// historyAltitude converts the altitude of a track history position, recorded in the
+// default datum of the tracks.
+func (ms *MainService) historyAltitude(p *pb.GeodeticPosition) TrackAltitude {
+	return ms.positionAltitude(float64(p.Latitude), float64(p.Longitude), float64(p.Altitude), parseVerticalDatum(ms.SvcConfig.GeoidConfig.TrackDatum))
+}
+
+// mslPosition returns the position with its altitude above the mean sea level.
+func mslPosition(p *pb.GeodeticPosition, altitude TrackAltitude) *pb.GeodeticPosition {
+	return &pb.GeodeticPosition{Latitude: p.Latitude, Longitude: p.Longitude, Altitude: float32(altitude.MSL)}
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	geo "172.21.5.249/air-trans/at-drone/internal/geo"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	volumeIndexTag      = "volume-index"
+	geofenceKeyPrefix   = "geofence/"
+	corridorKeyPrefix   = "corridor/"
+	corridorKeySegment  = "/"
+	defaultGridCellSize = 0.05
+)
+
+type indexedCorridor struct {
+	geometry  *corridorGeometry
+	version   uint32
+	updatedAt uint64
+	segments  int
+}
+
+type indexedGeofence struct {
+	geofence    *pb.Geofence
+	restriction bool
+}
+
+// volumeIndex keeps the active geofences and the corridor segments in a grid index so
+// that a track is only tested against the volumes around it. Corridors are indexed
+// when first evaluated and reindexed when their version changes.
+type volumeIndex struct {
+	mu        sync.RWMutex
+	radius    float64
+	turns     turnPerformance
+	heights   heightModels
+	grid      *geo.GridIndex
+	geofences map[string]*indexedGeofence
+	keepIn    map[string]*pb.Geofence
+	corridors map[string]*indexedCorridor
+}
+
+func newVolumeIndex(cfg config.ContainmentConfig, heights heightModels) *volumeIndex {
+	cellSize := cfg.IndexCellSize
+	if cellSize <= 0 {
+		cellSize = defaultGridCellSize
+	}
+
+	return &volumeIndex{
+		radius:    cfg.Radius,
+		turns:     newTurnPerformance(cfg),
+		heights:   heights,
+		grid:      geo.NewGridIndex(cellSize),
+		geofences: map[string]*indexedGeofence{},
+		keepIn:    map[string]*pb.Geofence{},
+		corridors: map[string]*indexedCorridor{},
+	}
+}
+
+func geofenceBBox(geofence *pb.Geofence) (geo.BBox, bool) {
+	vertices := geofence.GetVertices()
+	if len(vertices) == 0 {
+		return geo.BBox{}, false
+	}
+
+	b := geo.PointBBox(vertices[0].Latitude, vertices[0].Longitude)
+	for _, v := range vertices[1:] {
+		b = b.Extend(v.Latitude, v.Longitude)
+	}
+
+	return b, true
+}
+
+// PutGeofence indexes an active geofence, or drops it when it is not active anymore.
+// Restrictions are flight restrictions activated by the scheduler rather than stored geofences.
+func (v *volumeIndex) PutGeofence(geofence *pb.Geofence, restriction bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.putGeofence(geofence, restriction)
+}
+
+func (v *volumeIndex) putGeofence(geofence *pb.Geofence, restriction bool) {
+	v.removeGeofence(geofence.ID)
+
+	b, ok := geofenceBBox(geofence)
+	if !geofence.Active || !ok {
+		return
+	}
+
+	v.grid.Insert(geofenceKeyPrefix+geofence.ID, b)
+	v.geofences[geofence.ID] = &indexedGeofence{geofence: geofence, restriction: restriction}
+	if geofence.Type == pb.GEOFENCE_TYPE_GFT_KEEP_IN {
+		v.keepIn[geofence.ID] = geofence
+	}
+}
+
+func (v *volumeIndex) RemoveGeofence(id string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	_, ok := v.geofences[id]
+	v.removeGeofence(id)
+
+	return ok
+}
+
+func (v *volumeIndex) removeGeofence(id string) {
+	v.grid.Remove(geofenceKeyPrefix + id)
+	delete(v.geofences, id)
+	delete(v.keepIn, id)
+}
+
+// SyncGeofences replaces the stored geofences with the given active ones, leaving the
+// flight restrictions untouched. Unchanged geofences are not reindexed.
+func (v *volumeIndex) SyncGeofences(geofences []pb.Geofence) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	active := map[string]struct{}{}
+	for i := range geofences {
+		g := &geofences[i]
+		active[g.ID] = struct{}{}
+
+		if current, ok := v.geofences[g.ID]; ok && !current.restriction && current.geofence.UpdatedAt == g.UpdatedAt {
+			continue
+		}
+		v.putGeofence(g, false)
+	}
+
+	for id, g := range v.geofences {
+		if _, ok := active[id]; !ok && !g.restriction {
+			v.removeGeofence(id)
+		}
+	}
+}
+
+// GeofencesNear returns the geofences whose bounding box intersects b, plus every
+// keep-in geofence as a drone far from all of them still violates the nearest one.
+func (v *volumeIndex) GeofencesNear(b geo.BBox) []*pb.Geofence {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	rs := make([]*pb.Geofence, 0, len(v.keepIn))
+	for _, g := range v.keepIn {
+		rs = append(rs, g)
+	}
+
+	for _, key := range v.grid.Query(b) {
+		if !strings.HasPrefix(key, geofenceKeyPrefix) {
+			continue
+		}
+
+		g, ok := v.geofences[strings.TrimPrefix(key, geofenceKeyPrefix)]
+		if ok && g.geofence.Type != pb.GEOFENCE_TYPE_GFT_KEEP_IN {
+			rs = append(rs, g.geofence)
+		}
+	}
+
+	return rs
+}
+
+// CorridorGeometry returns the cached geometry of the corridor, building and indexing
+// it when the corridor is new or changed. Corridors without ID are not cached.
+func (v *volumeIndex) CorridorGeometry(corridor *pb.ContainmentCorridor) (*corridorGeometry, error) {
+	if corridor.ID == "" {
+		return newCorridorGeometry(corridor, v.turns, v.heights)
+	}
+
+	v.mu.RLock()
+	current, ok := v.corridors[corridor.ID]
+	v.mu.RUnlock()
+	if ok && current.version == corridor.Version && current.updatedAt == corridor.UpdatedAt {
+		return current.geometry, nil
+	}
+
+	geometry, err := newCorridorGeometry(corridor, v.turns, v.heights)
+	if err != nil {
+		return nil, err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.removeCorridor(corridor.ID)
+
+	waypoints := corridor.GetWaypoints()
+	for i := 0; i < len(waypoints)-1; i++ {
+		halfWidth, _, _ := segmentLimits(corridor, i, v.radius, 0)
+
+		b := geo.PointBBox(waypoints[i].Latitude, waypoints[i].Longitude).
+			Extend(waypoints[i+1].Latitude, waypoints[i+1].Longitude).
+			Buffer(halfWidth)
+		v.grid.Insert(corridorSegmentKey(corridor.ID, i), b)
+	}
+
+	v.corridors[corridor.ID] = &indexedCorridor{
+		geometry:  geometry,
+		version:   corridor.Version,
+		updatedAt: corridor.UpdatedAt,
+		segments:  len(waypoints) - 1,
+	}
+
+	return geometry, nil
+}
+
+func (v *volumeIndex) RemoveCorridor(id string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.removeCorridor(id)
+}
+
+func (v *volumeIndex) removeCorridor(id string) {
+	current, ok := v.corridors[id]
+	if !ok {
+		return
+	}
+
+	for i := 0; i < current.segments; i++ {
+		v.grid.Remove(corridorSegmentKey(id, i))
+	}
+	delete(v.corridors, id)
+}
+
+func corridorSegmentKey(id string, segment int) string {
+	return corridorKeyPrefix + id + corridorKeySegment + strconv.Itoa(segment)
+}
+
+// CorridorSegments returns the segments of the corridor whose buffered bounding box
+// contains the position, or nil when the corridor is not indexed with this geometry,
+// a caller on another version of the corridor having replaced it.
+func (v *volumeIndex) CorridorSegments(id string, geometry *corridorGeometry, lat, lon float64) []int {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	current, ok := v.corridors[id]
+	if !ok || current.geometry != geometry {
+		return nil
+	}
+
+	prefix := corridorKeyPrefix + id + corridorKeySegment
+	segments := []int{}
+	for _, key := range v.grid.Query(geo.PointBBox(lat, lon)) {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		segment, err := strconv.Atoi(strings.TrimPrefix(key, prefix))
+		if err == nil {
+			segments = append(segments, segment)
+		}
+	}
+
+	return segments
+}
+
+// CorridorsNear returns the IDs of the indexed corridors with a segment whose buffered
+// bounding box intersects b.
+func (v *volumeIndex) CorridorsNear(b geo.BBox) []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	seen := map[string]struct{}{}
+	ids := []string{}
+	for _, key := range v.grid.Query(b) {
+		if !strings.HasPrefix(key, corridorKeyPrefix) {
+			continue
+		}
+
+		id := strings.TrimPrefix(key, corridorKeyPrefix)
+		id = id[:strings.LastIndex(id, corridorKeySegment)]
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+func (ms *MainService) refreshVolumeIndex(ctx context.Context) error {
+	geofences, err := ms.FindActiveGeofences(ctx)
+	if err != nil {
+		return err
+	}
+
+	ms.volumes.SyncGeofences(geofences)
+
+	// Restrictions are indexed by their schedule, which only the instance that created one starts
+	err = ms.syncFlightRestrictions(ctx)
+	if err != nil {
+		config.PrintErrorLog(ctx, err, "Failed to schedule flight restrictions")
+	}
+
+	return nil
+}
+
+func (ms *MainService) volumeIndexJob() {
+	ctx := log.Logger.WithContext(context.Background())
+
+	ms.refreshVolumeIndex(ctx)
+}
+
+// StartVolumeIndex loads the active geofences and refreshes them periodically to pick
+// up changes made through other instances of the service, flight restrictions included.
+func (ms *MainService) StartVolumeIndex(ctx context.Context) error {
+	err := ms.refreshVolumeIndex(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load geofences: %w", err)
+	}
+
+	interval := time.Duration(ms.SvcConfig.ContainmentConfig.IndexRefresh) * time.Millisecond
+	if interval <= 0 {
+		return nil
+	}
+
+	_, err = ms.scheduler.Every(interval).SingletonMode().Tag(volumeIndexTag).Do(ms.volumeIndexJob)
+
+	return err
+}
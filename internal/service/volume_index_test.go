@@ -0,0 +1,83 @@
+package service
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	config "172.21.5.249/air-trans/at-drone/internal/config"
+	geo "172.21.5.249/air-trans/at-drone/internal/geo"
+	pb "172.21.5.249/air-trans/at-drone/pkg/pb"
+)
+
+const (
+	benchmarkTracks     = 500
+	benchmarkGeofences  = 5000
+	benchmarkAreaLat    = 21.0
+	benchmarkAreaLon    = 105.0
+	benchmarkAltitudeHi = 120.0
+)
+
+// benchmarkVolumeIndex indexes keep-out squares of up to 1 km scattered over a square degree.
+func benchmarkVolumeIndex(r *rand.Rand) *volumeIndex {
+	v := newVolumeIndex(config.ContainmentConfig{Radius: 5, IndexCellSize: 0.05}, heightModels{})
+	for i := 0; i < benchmarkGeofences; i++ {
+		lat, lon := benchmarkAreaLat+r.Float64(), benchmarkAreaLon+r.Float64()
+		half := 0.001 + 0.004*r.Float64()
+
+		v.PutGeofence(&pb.Geofence{
+			ID:   strconv.Itoa(i),
+			Type: pb.GEOFENCE_TYPE_GFT_KEEP_OUT,
+			Vertices: []*pb.GeofenceVertex{
+				{Latitude: lat - half, Longitude: lon - half},
+				{Latitude: lat - half, Longitude: lon + half},
+				{Latitude: lat + half, Longitude: lon + half},
+				{Latitude: lat + half, Longitude: lon - half},
+			},
+			MaxAltitude: benchmarkAltitudeHi,
+			Active:      true,
+		}, false)
+	}
+
+	return v
+}
+
+func BenchmarkVolumeIndexGeofencesNear(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	v := benchmarkVolumeIndex(r)
+
+	positions := make([][2]float64, benchmarkTracks)
+	for i := range positions {
+		positions[i] = [2]float64{benchmarkAreaLat + r.Float64(), benchmarkAreaLon + r.Float64()}
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, p := range positions {
+			checkGeofences(p[0], p[1], benchmarkAltitudeHi/2, v.GeofencesNear(geo.PointBBox(p[0], p[1])))
+		}
+	}
+}
+
+// BenchmarkGeofencesAll is the check of every track against every geofence the index replaces.
+func BenchmarkGeofencesAll(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	v := benchmarkVolumeIndex(r)
+
+	geofences := make([]*pb.Geofence, 0, len(v.geofences))
+	for _, g := range v.geofences {
+		geofences = append(geofences, g.geofence)
+	}
+
+	positions := make([][2]float64, benchmarkTracks)
+	for i := range positions {
+		positions[i] = [2]float64{benchmarkAreaLat + r.Float64(), benchmarkAreaLon + r.Float64()}
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, p := range positions {
+			checkGeofences(p[0], p[1], benchmarkAltitudeHi/2, geofences)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package terrain
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// TIFF tags
+const (
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagPlanarConfig    = 284
+	tagPredictor       = 317
+	tagTileWidth       = 322
+	tagTileLength      = 323
+	tagTileOffsets     = 324
+	tagTileByteCounts  = 325
+	tagSampleFormat    = 339
+	tagModelPixelScale = 33550
+	tagModelTiepoint   = 33922
+	tagGeoKeyDirectory = 34735
+	tagGDALNoData      = 42113
+)
+
+// GeoTIFF keys
+const (
+	keyModelType  = 1024
+	keyRasterType = 1025
+
+	modelTypeGeographic = 2
+	rasterPixelIsPoint  = 2
+)
+
+const (
+	compressionNone         = 1
+	compressionDeflate      = 8
+	compressionDeflateAdobe = 32946
+
+	sampleFormatInt   = 2
+	sampleFormatFloat = 3
+)
+
+// Byte size of the TIFF field types
+var tiffTypeSize = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+type tiffField struct {
+	typ   uint16
+	count int
+	raw   []byte
+}
+
+type tiffImage struct {
+	order  binary.ByteOrder
+	fields map[uint16]tiffField
+	width  int
+	height int
+}
+
+func (im *tiffImage) uints(tag uint16) []uint64 {
+	f, ok := im.fields[tag]
+	if !ok {
+		return nil
+	}
+
+	size := tiffTypeSize[f.typ]
+	values := make([]uint64, 0, f.count)
+	for i := 0; i < f.count; i++ {
+		b := f.raw[i*size:]
+		switch f.typ {
+		case 1, 7:
+			values = append(values, uint64(b[0]))
+		case 3:
+			values = append(values, uint64(im.order.Uint16(b)))
+		case 4:
+			values = append(values, uint64(im.order.Uint32(b)))
+		default:
+			return nil
+		}
+	}
+
+	return values
+}
+
+func (im *tiffImage) uint(tag uint16, fallback uint64) uint64 {
+	values := im.uints(tag)
+	if len(values) == 0 {
+		return fallback
+	}
+
+	return values[0]
+}
+
+func (im *tiffImage) floats(tag uint16) []float64 {
+	f, ok := im.fields[tag]
+	if !ok || f.typ != 12 {
+		return nil
+	}
+
+	values := make([]float64, f.count)
+	for i := range values {
+		values[i] = math.Float64frombits(im.order.Uint64(f.raw[8*i:]))
+	}
+
+	return values
+}
+
+func (im *tiffImage) ascii(tag uint16) string {
+	f, ok := im.fields[tag]
+	if !ok || f.typ != 2 {
+		return ""
+	}
+
+	return strings.TrimRight(string(f.raw), "\x00 ")
+}
+
+// parseTIFF reads the first image directory of a classic TIFF file.
+func parseTIFF(r io.ReaderAt) (*tiffImage, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+
+	im := &tiffImage{fields: map[uint16]tiffField{}}
+	switch string(header[:2]) {
+	case "II":
+		im.order = binary.LittleEndian
+	case "MM":
+		im.order = binary.BigEndian
+	default:
+		return nil, errors.New("not a TIFF file")
+	}
+	if im.order.Uint16(header[2:]) != 42 {
+		return nil, errors.New("unsupported TIFF version, BigTIFF is not supported")
+	}
+
+	offset := int64(im.order.Uint32(header[4:]))
+	countBytes := make([]byte, 2)
+	if _, err := r.ReadAt(countBytes, offset); err != nil {
+		return nil, err
+	}
+
+	entries := make([]byte, 12*int(im.order.Uint16(countBytes)))
+	if _, err := r.ReadAt(entries, offset+2); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < len(entries); i += 12 {
+		e := entries[i : i+12]
+		tag, typ, count := im.order.Uint16(e), im.order.Uint16(e[2:]), int(im.order.Uint32(e[4:]))
+
+		size, ok := tiffTypeSize[typ]
+		if !ok {
+			continue
+		}
+
+		raw := make([]byte, size*count)
+		if len(raw) <= 4 {
+			copy(raw, e[8:])
+		} else if _, err := r.ReadAt(raw, int64(im.order.Uint32(e[8:]))); err != nil {
+			return nil, err
+		}
+
+		im.fields[tag] = tiffField{typ: typ, count: count, raw: raw}
+	}
+
+	im.width = int(im.uint(tagImageWidth, 0))
+	im.height = int(im.uint(tagImageLength, 0))
+	if im.width == 0 || im.height == 0 {
+		return nil, errors.New("TIFF image has no size")
+	}
+
+	return im, nil
+}
+
+// georeference returns the position of the first sample and the sample spacing in
+// degree. Rasters in projected coordinates are not supported.
+func (im *tiffImage) georeference() (north, west, dLat, dLon float64, err error) {
+	scale, tiepoint := im.floats(tagModelPixelScale), im.floats(tagModelTiepoint)
+	if len(scale) < 2 || len(tiepoint) < 6 {
+		return 0, 0, 0, 0, errors.New("GeoTIFF has no pixel scale or tie point")
+	}
+
+	rasterType := uint64(0)
+	keys := im.uints(tagGeoKeyDirectory)
+	for i := 4; i+3 < len(keys); i += 4 {
+		switch keys[i] {
+		case keyModelType:
+			if keys[i+1] == 0 && keys[i+3] != modelTypeGeographic {
+				return 0, 0, 0, 0, errors.New("GeoTIFF is not in geographic coordinates")
+			}
+		case keyRasterType:
+			rasterType = keys[i+3]
+		}
+	}
+
+	dLon, dLat = scale[0], scale[1]
+	west = tiepoint[3] - tiepoint[0]*dLon
+	north = tiepoint[4] + tiepoint[1]*dLat
+	if rasterType != rasterPixelIsPoint {
+		west += dLon / 2
+		north -= dLat / 2
+	}
+
+	return north, west, dLat, dLon, nil
+}
+
+// geotiffInfo reads the extent of a GeoTIFF from its header.
+func geotiffInfo(path string) (*tileInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	im, err := parseTIFF(f)
+	if err != nil {
+		return nil, err
+	}
+
+	north, west, dLat, dLon, err := im.georeference()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tileInfo{
+		path:   path,
+		format: tileGeoTIFF,
+		north:  north + dLat/2,
+		west:   west - dLon/2,
+		south:  north - float64(im.height-1)*dLat - dLat/2,
+		east:   west + float64(im.width-1)*dLon + dLon/2,
+	}, nil
+}
+
+// readGeoTIFF loads a single band GeoTIFF of integer or floating point samples, stored
+// in strips or tiles, uncompressed or deflated.
+func readGeoTIFF(path string) (*grid, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	im, err := parseTIFF(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	north, west, dLat, dLon, err := im.georeference()
+	if err != nil {
+		return nil, err
+	}
+
+	if im.uint(tagSamplesPerPixel, 1) != 1 {
+		return nil, errors.New("GeoTIFF has more than one band")
+	}
+
+	decode, size, err := im.sampleDecoder()
+	if err != nil {
+		return nil, err
+	}
+
+	g := &grid{
+		north:  north,
+		west:   west,
+		dLat:   dLat,
+		dLon:   dLon,
+		rows:   im.height,
+		cols:   im.width,
+		values: make([]float32, im.width*im.height),
+	}
+	if noData := im.ascii(tagGDALNoData); noData != "" {
+		v, err := strconv.ParseFloat(noData, 32)
+		if err == nil {
+			g.noData, g.hasNoData = float32(v), true
+		}
+	}
+
+	// Chunks are strips the width of the image or tiles
+	chunkWidth, chunkHeight := im.width, int(im.uint(tagRowsPerStrip, uint64(im.height)))
+	offsets, counts := im.uints(tagStripOffsets), im.uints(tagStripByteCounts)
+	across := 1
+	if _, tiled := im.fields[tagTileWidth]; tiled {
+		chunkWidth, chunkHeight = int(im.uint(tagTileWidth, 0)), int(im.uint(tagTileLength, 0))
+		offsets, counts = im.uints(tagTileOffsets), im.uints(tagTileByteCounts)
+		if chunkWidth == 0 || chunkHeight == 0 {
+			return nil, errors.New("GeoTIFF tiles have no size")
+		}
+		across = (im.width + chunkWidth - 1) / chunkWidth
+	}
+	if len(offsets) == 0 || len(offsets) != len(counts) {
+		return nil, errors.New("GeoTIFF has no sample offsets")
+	}
+
+	for i := range offsets {
+		if offsets[i]+counts[i] > uint64(len(data)) {
+			return nil, errors.New("GeoTIFF sample data out of the file")
+		}
+
+		chunk, err := im.decompress(data[offsets[i] : offsets[i]+counts[i]])
+		if err != nil {
+			return nil, err
+		}
+		if err := im.unpredict(chunk, chunkWidth, size); err != nil {
+			return nil, err
+		}
+
+		row0, col0 := (i/across)*chunkHeight, (i%across)*chunkWidth
+		for r := 0; r < chunkHeight && row0+r < im.height; r++ {
+			for c := 0; c < chunkWidth && col0+c < im.width; c++ {
+				at := (r*chunkWidth + c) * size
+				if at+size > len(chunk) {
+					return nil, errors.New("GeoTIFF chunk is truncated")
+				}
+				g.values[(row0+r)*im.width+col0+c] = decode(chunk[at:])
+			}
+		}
+	}
+
+	return g, nil
+}
+
+func (im *tiffImage) decompress(chunk []byte) ([]byte, error) {
+	switch compression := im.uint(tagCompression, compressionNone); compression {
+	case compressionNone:
+		return chunk, nil
+	case compressionDeflate, compressionDeflateAdobe:
+		r, err := zlib.NewReader(bytes.NewReader(chunk))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		return io.ReadAll(r)
+	default:
+		return nil, fmt.Errorf("unsupported GeoTIFF compression %d", compression)
+	}
+}
+
+// unpredict undoes the horizontal differencing of integer samples.
+func (im *tiffImage) unpredict(chunk []byte, width int, size int) error {
+	switch predictor := im.uint(tagPredictor, 1); predictor {
+	case 1:
+		return nil
+	case 2:
+	default:
+		return fmt.Errorf("unsupported GeoTIFF predictor %d", predictor)
+	}
+
+	rowBytes := width * size
+	for row := 0; row+rowBytes <= len(chunk); row += rowBytes {
+		for at := row + size; at < row+rowBytes; at += size {
+			switch size {
+			case 1:
+				chunk[at] += chunk[at-1]
+			case 2:
+				im.order.PutUint16(chunk[at:], im.order.Uint16(chunk[at:])+im.order.Uint16(chunk[at-2:]))
+			case 4:
+				im.order.PutUint32(chunk[at:], im.order.Uint32(chunk[at:])+im.order.Uint32(chunk[at-4:]))
+			default:
+				return fmt.Errorf("unsupported GeoTIFF predictor for %d-byte samples", size)
+			}
+		}
+	}
+
+	return nil
+}
+
+// sampleDecoder returns the decoder and the byte size of a sample.
+func (im *tiffImage) sampleDecoder() (func([]byte) float32, int, error) {
+	bits := im.uint(tagBitsPerSample, 1)
+	format := im.uint(tagSampleFormat, 1)
+	order := im.order
+
+	switch {
+	case format == sampleFormatFloat && bits == 32:
+		return func(b []byte) float32 { return math.Float32frombits(order.Uint32(b)) }, 4, nil
+	case format == sampleFormatFloat && bits == 64:
+		return func(b []byte) float32 { return float32(math.Float64frombits(order.Uint64(b))) }, 8, nil
+	case format == sampleFormatInt && bits == 16:
+		return func(b []byte) float32 { return float32(int16(order.Uint16(b))) }, 2, nil
+	case format == sampleFormatInt && bits == 32:
+		return func(b []byte) float32 { return float32(int32(order.Uint32(b))) }, 4, nil
+	case format != sampleFormatFloat && bits == 8:
+		return func(b []byte) float32 { return float32(b[0]) }, 1, nil
+	case format != sampleFormatFloat && bits == 16:
+		return func(b []byte) float32 { return float32(order.Uint16(b)) }, 2, nil
+	case format != sampleFormatFloat && bits == 32:
+		return func(b []byte) float32 { return float32(order.Uint32(b)) }, 4, nil
+	}
+
+	return nil, 0, fmt.Errorf("unsupported GeoTIFF samples of %d bits in format %d", bits, format)
+}
@@ -0,0 +1,76 @@
+package terrain
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Void sample of SRTM tiles
+const hgtVoid = -32768
+
+var hgtName = regexp.MustCompile(`^([NS])(\d{2})([EW])(\d{3})$`)
+
+// hgtInfo reads the extent of an SRTM tile from its name, N37W122.hgt covering
+// latitudes 37 to 38 and longitudes -122 to -121.
+func hgtInfo(path string) (*tileInfo, error) {
+	name := strings.ToUpper(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	match := hgtName.FindStringSubmatch(name)
+	if match == nil {
+		return nil, fmt.Errorf("not an SRTM tile name: %s", filepath.Base(path))
+	}
+
+	lat, _ := strconv.Atoi(match[2])
+	lon, _ := strconv.Atoi(match[4])
+	if match[1] == "S" {
+		lat = -lat
+	}
+	if match[3] == "W" {
+		lon = -lon
+	}
+
+	return &tileInfo{
+		path:   path,
+		format: tileHGT,
+		south:  float64(lat),
+		west:   float64(lon),
+		north:  float64(lat + 1),
+		east:   float64(lon + 1),
+	}, nil
+}
+
+// readHGT loads the big-endian 16-bit samples of an SRTM tile, 1201 or 3601 squared,
+// the first one on the north west corner.
+func readHGT(info *tileInfo) (*grid, error) {
+	data, err := os.ReadFile(info.path)
+	if err != nil {
+		return nil, err
+	}
+
+	size := int(math.Sqrt(float64(len(data) / 2)))
+	if size < 2 || size*size*2 != len(data) {
+		return nil, fmt.Errorf("unexpected SRTM tile size %d bytes", len(data))
+	}
+
+	g := &grid{
+		north:     info.north,
+		west:      info.west,
+		dLat:      1 / float64(size-1),
+		dLon:      1 / float64(size-1),
+		rows:      size,
+		cols:      size,
+		values:    make([]float32, size*size),
+		noData:    hgtVoid,
+		hasNoData: true,
+	}
+	for i := range g.values {
+		g.values[i] = float32(int16(binary.BigEndian.Uint16(data[2*i:])))
+	}
+
+	return g, nil
+}
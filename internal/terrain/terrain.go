@@ -0,0 +1,235 @@
+// Package terrain samples ground elevations from the DEM tiles of a local directory,
+// SRTM .hgt files and GeoTIFF rasters in geographic coordinates.
+//
+// Latitudes and longitudes are in degree and elevations in meter above mean sea level,
+// as stored in the tiles.
+package terrain
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var ErrNoData = errors.New("no terrain data")
+
+type tileFormat int
+
+const (
+	tileHGT tileFormat = iota
+	tileGeoTIFF
+)
+
+// tileInfo is the extent of a tile file, read without loading its samples.
+type tileInfo struct {
+	path   string
+	format tileFormat
+	south  float64
+	west   float64
+	north  float64
+	east   float64
+}
+
+func (t *tileInfo) contains(lat, lon float64) bool {
+	return lat >= t.south && lat <= t.north && lon >= t.west && lon <= t.east
+}
+
+// grid is a raster of samples, rows from north to south. north and west locate the
+// first sample.
+type grid struct {
+	north     float64
+	west      float64
+	dLat      float64
+	dLon      float64
+	rows      int
+	cols      int
+	values    []float32
+	noData    float32
+	hasNoData bool
+}
+
+func (g *grid) valid(v float32) bool {
+	return !(g.hasNoData && v == g.noData) && !math.IsNaN(float64(v))
+}
+
+// at interpolates bilinearly between the four samples around the position, leaving
+// out the voids. Positions within half a sample of the outer samples are clamped on them.
+func (g *grid) at(lat, lon float64) (float64, bool) {
+	y := (g.north - lat) / g.dLat
+	x := (lon - g.west) / g.dLon
+	if y < -0.5 || x < -0.5 || y > float64(g.rows)-0.5 || x > float64(g.cols)-0.5 {
+		return 0, false
+	}
+	y = math.Max(0, math.Min(float64(g.rows-1), y))
+	x = math.Max(0, math.Min(float64(g.cols-1), x))
+
+	r0, c0 := int(y), int(x)
+	r1, c1 := min(r0+1, g.rows-1), min(c0+1, g.cols-1)
+	fy, fx := y-float64(r0), x-float64(c0)
+
+	sum, weights := 0.0, 0.0
+	for _, s := range []struct {
+		r, c int
+		w    float64
+	}{
+		{r0, c0, (1 - fy) * (1 - fx)},
+		{r0, c1, (1 - fy) * fx},
+		{r1, c0, fy * (1 - fx)},
+		{r1, c1, fy * fx},
+	} {
+		v := g.values[s.r*g.cols+s.c]
+		if s.w == 0 || !g.valid(v) {
+			continue
+		}
+		sum += float64(v) * s.w
+		weights += s.w
+	}
+
+	if weights == 0 {
+		return 0, false
+	}
+
+	return sum / weights, true
+}
+
+// Model answers ground elevations from the tiles found in a directory, keeping the
+// most recently used ones in memory.
+type Model struct {
+	mu       sync.Mutex
+	hgt      map[[2]int]*tileInfo // by south west corner
+	geotiffs []*tileInfo
+	capacity int
+	lru      *list.List // of *cachedTile, most recent first
+	cached   map[string]*list.Element
+}
+
+type cachedTile struct {
+	path string
+	grid *grid
+	err  error
+}
+
+// New indexes the tiles of dir. Files that are not readable tiles are left out. An
+// empty dir gives a model without data.
+func New(dir string, cacheTiles int) (*Model, error) {
+	if cacheTiles < 1 {
+		cacheTiles = 1
+	}
+
+	m := &Model{
+		hgt:      map[[2]int]*tileInfo{},
+		capacity: cacheTiles,
+		lru:      list.New(),
+		cached:   map[string]*list.Element{},
+	}
+	if dir == "" {
+		return m, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(dir, e.Name())
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".hgt":
+			info, err := hgtInfo(path)
+			if err == nil {
+				m.hgt[[2]int{int(info.south), int(info.west)}] = info
+			}
+		case ".tif", ".tiff":
+			info, err := geotiffInfo(path)
+			if err == nil {
+				m.geotiffs = append(m.geotiffs, info)
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// Tiles returns the number of indexed tiles.
+func (m *Model) Tiles() int {
+	if m == nil {
+		return 0
+	}
+
+	return len(m.hgt) + len(m.geotiffs)
+}
+
+// Elevation returns the ground elevation at the position, ErrNoData when no tile
+// covers it or the samples around are voids.
+func (m *Model) Elevation(lat, lon float64) (float64, error) {
+	if m == nil {
+		return 0, ErrNoData
+	}
+
+	candidates := make([]*tileInfo, 0, 2)
+	if info, ok := m.hgt[[2]int{int(math.Floor(lat)), int(math.Floor(lon))}]; ok {
+		candidates = append(candidates, info)
+	}
+	for _, info := range m.geotiffs {
+		if info.contains(lat, lon) {
+			candidates = append(candidates, info)
+		}
+	}
+
+	for _, info := range candidates {
+		g, err := m.load(info)
+		if err != nil {
+			return 0, err
+		}
+
+		if elevation, ok := g.at(lat, lon); ok {
+			return elevation, nil
+		}
+	}
+
+	return 0, ErrNoData
+}
+
+// load returns the samples of the tile from the cache, reading the file on a miss and
+// evicting the least recently used tile beyond the capacity.
+func (m *Model) load(info *tileInfo) (*grid, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.cached[info.path]; ok {
+		m.lru.MoveToFront(e)
+		c := e.Value.(*cachedTile)
+
+		return c.grid, c.err
+	}
+
+	var g *grid
+	var err error
+	switch info.format {
+	case tileHGT:
+		g, err = readHGT(info)
+	case tileGeoTIFF:
+		g, err = readGeoTIFF(info.path)
+	}
+	if err != nil {
+		err = fmt.Errorf("terrain tile %s: %w", filepath.Base(info.path), err)
+	}
+
+	m.cached[info.path] = m.lru.PushFront(&cachedTile{path: info.path, grid: g, err: err})
+	for m.lru.Len() > m.capacity {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.cached, oldest.Value.(*cachedTile).path)
+	}
+
+	return g, err
+}
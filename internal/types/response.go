@@ -18,6 +18,12 @@ type ErrorResponse struct {
 	Message string `json:"message" example:"status bad request"`
 }
 
+type ValidationErrorResponse struct {
+	Code    int         `json:"code" example:"400"`
+	Message string      `json:"message" example:"invalid route"`
+	Errors  interface{} `json:"errors"`
+}
+
 type SucceedResponse struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`